@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdAdd(opts *RootCmdOptions) *cobra.Command {
+	editOpts := &editOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "add <pattern> <owners>...",
+		Short: "Add owners for a pattern to the CODEOWNERS file",
+		Long: `Give the owners the files matched by the pattern. When the CODEOWNERS file already has a rule for the
+pattern the owners are added to it, otherwise a new rule is added after every rule that could match the same
+files, so it isn't shadowed, and before the rules for directories inside the pattern so they keep their owners.
+
+The changed lines and the files that would change owners are shown before anything is written.`,
+		Example: `  $ gh codeowners add /services/billing/ @org/billing
+  $ gh codeowners add "*.sql" @org/dba @octocat --yes`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editCodeowners(cmd, opts, editOpts, func(document *codeowners.Document) ([]codeowners.DocumentChange, error) {
+				return document.AddRule(args[0], args[1:])
+			})
+		},
+	}
+
+	editOpts.addFlags(cmd)
+
+	return cmd
+}
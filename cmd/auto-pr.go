@@ -12,6 +12,7 @@ import (
 	"text/template"
 
 	"github.com/cli/cli/v2/pkg/githubtemplate"
+	"github.com/justindbaur/gh-codeowners/codeowners"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +25,8 @@ type AutoPROptions struct {
 	UnownedFiles string
 	DryRun       bool
 	Template     string
+	// Either owner or section, grouping by section makes one PR per GitLab section
+	GroupBy string
 }
 
 func newCmdAutoPR(opts *RootCmdOptions) *cobra.Command {
@@ -41,26 +44,70 @@ common prefixes and suffixes removed, 'Files' which is a slice of the files bein
 a link to this tool. You can also invoke the '{{ .Input "my_value" }} function. This lets you prompt yourself for a value for
 each team.'`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			edittedFilesScanner, err := GetEdittedFilesScanner(cmd, opts)
+			if autoPROpts.GroupBy != "owner" && autoPROpts.GroupBy != "section" {
+				return fmt.Errorf("invalid --group-by '%s', expected owner or section", autoPROpts.GroupBy)
+			}
+
+			kinds, err := GetOwnerKinds(cmd)
+
+			if err != nil {
+				return err
+			}
+
+			changes, err := GetChanges(cmd, opts)
 
 			if err != nil {
-				return fmt.Errorf("error getting editted files scanner: %v", err)
+				return fmt.Errorf("error getting changed files: %v", err)
 			}
 
-			codeowners, err := GetCodeowners(cmd, opts)
+			remoteName, err := opts.GetRemoteName()
+
+			if err != nil {
+				return fmt.Errorf("could not determine remote name: %v", err)
+			}
+
+			var codeowners *codeowners.Codeowners
+
+			if cmd.Flags().Changed("codeowners-ref") {
+				codeowners, err = GetCodeowners(cmd, opts)
+			} else {
+				codeowners, err = getBaseCodeowners(cmd, opts, remoteName)
+			}
 
 			if err != nil {
 				return fmt.Errorf("error getting codeowners info: %v", err)
 			}
 
-			filesMap := map[string][]string{}
-			unownedFiles := []string{}
+			filesMap := map[string][]Change{}
+			unownedFiles := []Change{}
+			intentionallyUnownedFiles := []Change{}
+			filteredOwnerFiles := []Change{}
 
-			for edittedFilesScanner.Scan() {
-				owners := codeowners.FindOwners(edittedFilesScanner.Bytes())
+			// Owners that only differ by case are the same team and go on the same PR
+			teamNames := ownerSpellings{}
+
+			for _, change := range changes {
+				// Renames involve the owners of both the old and new path
+				owners := []string{}
+
+				if autoPROpts.GroupBy == "section" {
+					if section := changeSection(codeowners, change, kinds); section != nil {
+						owners = append(owners, sectionName(section))
+					}
+				} else {
+					for _, owner := range changeOwners(codeowners, change, kinds) {
+						owners = append(owners, teamNames.name(owner))
+					}
+				}
 
 				if len(owners) == 0 {
-					unownedFiles = append(unownedFiles, edittedFilesScanner.Text())
+					if changeOwnersFilteredOut(codeowners, change, kinds) {
+						filteredOwnerFiles = append(filteredOwnerFiles, change)
+					} else if changeIntentionallyUnowned(codeowners, change) {
+						intentionallyUnownedFiles = append(intentionallyUnownedFiles, change)
+					} else {
+						unownedFiles = append(unownedFiles, change)
+					}
 					continue
 				}
 
@@ -72,60 +119,27 @@ each team.'`,
 					if found {
 						// Update
 						foundEntry = true
-						filesMap[owner] = append(existingValue, edittedFilesScanner.Text())
+						filesMap[owner] = append(existingValue, change)
 					}
 				}
 
 				if !foundEntry {
 					// Insert it for the first owner
-					filesMap[owners[0]] = []string{edittedFilesScanner.Text()}
+					filesMap[owners[0]] = []Change{change}
 				}
 			}
 
-			if len(unownedFiles) > 0 {
-				// Let the user choose where to put unowned files
-				options := append(slices.Collect(maps.Keys(filesMap)), "Separate", "Choose for each")
-				optionIndex, err := opts.Prompter.Select(fmt.Sprintf("Choose where to put %d unowned files", len(unownedFiles)), "", options)
-
-				if err != nil {
-					return fmt.Errorf("error requesting what to do with unowned files: %v", err)
-				}
-
-				option := options[optionIndex]
-
-				if option == "Choose for each" {
-					eachOptions := append(slices.Collect(maps.Keys(filesMap)), "Separate")
-					for _, unownedFile := range unownedFiles {
-						eachOptionIndex, err := opts.Prompter.Select(fmt.Sprintf("Choose where to put %s", unownedFile), "", eachOptions)
-
-						if err != nil {
-							return fmt.Errorf("issue getting PR to put %s: %v", unownedFile, err)
-						}
-
-						eachOption := eachOptions[eachOptionIndex]
-
-						existingValue, found := filesMap[eachOption]
+			if err := placeUnownedChanges(opts, filesMap, unownedFiles, "unowned files"); err != nil {
+				return err
+			}
 
-						// TODO: Use AddOrUpdate when merged
-						if found {
-							// Append
-							filesMap[eachOption] = append(existingValue, unownedFile)
-						} else {
-							// Insert
-							filesMap[eachOption] = []string{unownedFile}
-						}
-					}
-				} else {
-					existingValue, found := filesMap[option]
+			// Files CODEOWNERS deliberately leaves without owners still need to go in one of the PR's
+			if err := placeUnownedChanges(opts, filesMap, intentionallyUnownedFiles, "files CODEOWNERS leaves unowned on purpose"); err != nil {
+				return err
+			}
 
-					if found {
-						// Append
-						filesMap[option] = append(existingValue, unownedFiles...)
-					} else {
-						// Insert
-						filesMap[option] = unownedFiles
-					}
-				}
+			if err := placeUnownedChanges(opts, filesMap, filteredOwnerFiles, "files without owners of the chosen kinds"); err != nil {
+				return err
 			}
 
 			if len(filesMap) == 0 {
@@ -155,10 +169,10 @@ each team.'`,
 				return fmt.Errorf("error getting body template: %v", err)
 			}
 
-			remoteName, err := opts.GetRemoteName()
+			root, err := GetRepoRoot(opts)
 
 			if err != nil {
-				return fmt.Errorf("could not determine remote name: %v", err)
+				return err
 			}
 
 			// TODO: Possibly remove "Separate" from the PR's to make short names from
@@ -170,7 +184,14 @@ each team.'`,
 			checkedOutBranches := []string{}
 
 			// TODO: Do this loop with some sort that makes it do it the same way each time
-			for team, files := range filesMap {
+			for team, teamChanges := range filesMap {
+				files := make([]string, len(teamChanges))
+				paths := []string{}
+				for i, change := range teamChanges {
+					files[i] = change.String()
+					paths = append(paths, change.Paths()...)
+				}
+
 				templateData := &TemplateData{
 					Number:     number,
 					TeamId:     team,
@@ -216,7 +237,7 @@ each team.'`,
 				}
 
 				// Stage files for this team
-				addOutput, err := opts.GitExec(append([]string{"add"}, files...)...)
+				addOutput, err := opts.GitExec(addArgs(root, paths)...)
 
 				if err != nil {
 					// Possible errors:
@@ -318,10 +339,14 @@ each team.'`,
 		},
 	}
 
+	addChangeFlags(cmd)
+	addOwnerKindFlag(cmd)
+
 	fl := cmd.Flags()
 	fl.StringVarP(&autoPROpts.CommitTemplate, "commit", "c", "", "The template string to use for each commit")
 	fl.StringVarP(&autoPROpts.BranchTemplate, "branch", "b", "", "The template string to use for each branch that is created")
 	fl.StringVarP(&autoPROpts.UnownedFiles, "unowned-files", "u", "", "What PR to put unowned files onto. `separate` to make their own PR.")
+	fl.StringVar(&autoPROpts.GroupBy, "group-by", "owner", "Make a PR per `owner` or per GitLab CODEOWNERS section")
 	fl.BoolVarP(&autoPROpts.IsDraft, "draft", "d", false, "Mark the pull requests as drafts")
 	fl.BoolVar(&autoPROpts.DryRun, "dry-run", false, "Print details instead of creating the PR. May still push git changes.")
 	fl.StringVarP(&autoPROpts.Template, "template", "T", "", "The template `file` to use when creating the templated team PR")
@@ -334,6 +359,106 @@ each team.'`,
 	return cmd
 }
 
+// changeSection picks the section whose PR a change goes on: the first named section that requires
+// approval and has owners for the change, otherwise the default section when it has owners for it.
+// Returns nil when no section has owners for the change.
+func changeSection(co *codeowners.Codeowners, change Change, kinds []codeowners.OwnerKind) *codeowners.Section {
+	sectionOwners := changeSectionOwners(co, change, kinds)
+
+	var fallback *codeowners.Section
+
+	for _, section := range co.Sections() {
+		if len(sectionOwners[section]) == 0 {
+			continue
+		}
+
+		if !section.IsDefault() && !section.Optional {
+			return section
+		}
+
+		if fallback == nil {
+			fallback = section
+		}
+	}
+
+	return fallback
+}
+
+// placeUnownedChanges lets the user choose which PR's the given changes without owners go onto
+func placeUnownedChanges(opts *RootCmdOptions, filesMap map[string][]Change, unownedFiles []Change, description string) error {
+	if len(unownedFiles) == 0 {
+		return nil
+	}
+
+	options := append(slices.Collect(maps.Keys(filesMap)), "Separate", "Choose for each")
+	optionIndex, err := opts.Prompter.Select(fmt.Sprintf("Choose where to put %d %s", len(unownedFiles), description), "", options)
+
+	if err != nil {
+		return fmt.Errorf("error requesting what to do with %s: %v", description, err)
+	}
+
+	option := options[optionIndex]
+
+	if option == "Choose for each" {
+		eachOptions := append(slices.Collect(maps.Keys(filesMap)), "Separate")
+		for _, unownedFile := range unownedFiles {
+			eachOptionIndex, err := opts.Prompter.Select(fmt.Sprintf("Choose where to put %s", unownedFile), "", eachOptions)
+
+			if err != nil {
+				return fmt.Errorf("issue getting PR to put %s: %v", unownedFile, err)
+			}
+
+			eachOption := eachOptions[eachOptionIndex]
+
+			existingValue, found := filesMap[eachOption]
+
+			// TODO: Use AddOrUpdate when merged
+			if found {
+				// Append
+				filesMap[eachOption] = append(existingValue, unownedFile)
+			} else {
+				// Insert
+				filesMap[eachOption] = []Change{unownedFile}
+			}
+		}
+	} else {
+		existingValue, found := filesMap[option]
+
+		if found {
+			// Append
+			filesMap[option] = append(existingValue, unownedFiles...)
+		} else {
+			// Insert
+			filesMap[option] = unownedFiles
+		}
+	}
+
+	return nil
+}
+
+// getBaseCodeowners parses the CODEOWNERS file from the branch the PR's will target, since that's the one GitHub
+// uses to request reviews. Falls back to the working tree if that branch can't be read.
+func getBaseCodeowners(cmd *cobra.Command, rootOpts *RootCmdOptions, remoteName string) (*codeowners.Codeowners, error) {
+	stdOut, _, err := rootOpts.GhExec("repo", "view", "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name")
+	baseBranch := strings.TrimSpace(stdOut.String())
+
+	if err != nil || baseBranch == "" {
+		cmd.PrintErrln("warning: could not determine the default branch, using the CODEOWNERS file in the working tree")
+		return GetCodeownersAt(cmd, rootOpts, "")
+	}
+
+	baseRef := fmt.Sprintf("%s/%s", remoteName, baseBranch)
+	baseCodeowners, err := GetCodeownersAt(cmd, rootOpts, baseRef)
+
+	if err != nil {
+		cmd.PrintErrf("warning: %v, using the CODEOWNERS file in the working tree\n", err)
+		return GetCodeownersAt(cmd, rootOpts, "")
+	}
+
+	cmd.Printf("Using the CODEOWNERS file from %s\n", baseRef)
+	return baseCodeowners, nil
+}
+
 func getBranchTemplate(cmd *cobra.Command, rootOpts *RootCmdOptions, autoPrOpts *AutoPROptions) (*template.Template, error) {
 	var templateString = ""
 
@@ -392,14 +517,12 @@ func getBodyTemplate(cmd *cobra.Command, rootOpts *RootCmdOptions, autoPrOpts *A
 	var initialPrContents = ""
 
 	if autoPrOpts.Template == "" {
-		topLevelDirBytes, err := rootOpts.GitExec("rev-parse", "--show-toplevel")
+		topLevelDir, err := GetRepoRoot(rootOpts)
 
 		if err != nil {
-			return nil, fmt.Errorf("could not find top level dir: %v", err)
+			return nil, err
 		}
 
-		topLevelDir := strings.Trim(string(topLevelDirBytes), "\n")
-
 		const filePattern = "PULL_REQUEST_TEMPLATE"
 
 		// Get the top level dir another way
@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+type ChangeStatus string
+
+const (
+	ChangeAdded    ChangeStatus = "added"
+	ChangeModified ChangeStatus = "modified"
+	ChangeDeleted  ChangeStatus = "deleted"
+	ChangeRenamed  ChangeStatus = "renamed"
+)
+
+// Change is a single file change, for deletions Path is the path of the deleted file
+type Change struct {
+	Status ChangeStatus
+	Path   string
+	// Path the file was renamed from, only set for renames
+	OldPath string
+}
+
+// Paths returns every path touched by the change
+func (c Change) Paths() []string {
+	if c.OldPath != "" {
+		return []string{c.Path, c.OldPath}
+	}
+
+	return []string{c.Path}
+}
+
+func (c Change) String() string {
+	if c.OldPath != "" {
+		return fmt.Sprintf("%s -> %s", c.OldPath, c.Path)
+	}
+
+	return c.Path
+}
+
+// changeOwners returns the owners of every path touched by the change, so a file moved between
+// teams involves both of them. Only owners of the given kinds are included, or every kind when
+// kinds is empty. Owners GitHub would ignore because they aren't valid are left out.
+func changeOwners(co *codeowners.Codeowners, change Change, kinds []codeowners.OwnerKind) []codeowners.Owner {
+	owners := []codeowners.Owner{}
+
+	for _, path := range change.Paths() {
+		for _, owner := range co.FindParsedOwners([]byte(path)) {
+			if len(kinds) > 0 && !slices.Contains(kinds, owner.Kind) {
+				continue
+			}
+
+			if !slices.ContainsFunc(owners, owner.Equal) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	return owners
+}
+
+// changeSectionOwners returns the owners each section picks for the change, for files with GitLab
+// sections. Sections that don't match either path of the change are left out.
+func changeSectionOwners(co *codeowners.Codeowners, change Change, kinds []codeowners.OwnerKind) map[*codeowners.Section][]codeowners.Owner {
+	sectionOwners := map[*codeowners.Section][]codeowners.Owner{}
+
+	for _, path := range change.Paths() {
+		for _, match := range co.MatchSections([]byte(path)) {
+			owners, found := sectionOwners[match.Section]
+			if !found {
+				owners = []codeowners.Owner{}
+			}
+
+			for _, owner := range match.Entry.ParsedOwners() {
+				if len(kinds) > 0 && !slices.Contains(kinds, owner.Kind) {
+					continue
+				}
+
+				if !slices.ContainsFunc(owners, owner.Equal) {
+					owners = append(owners, owner)
+				}
+			}
+
+			sectionOwners[match.Section] = owners
+		}
+	}
+
+	return sectionOwners
+}
+
+// sectionName is how a section is shown to the user, the default section doesn't have a name
+func sectionName(section *codeowners.Section) string {
+	if section.IsDefault() {
+		return "Default"
+	}
+
+	return section.Name
+}
+
+// ownerNames returns the owners as they are written in CODEOWNERS
+func ownerNames(owners []codeowners.Owner) []string {
+	names := make([]string, len(owners))
+
+	for i, owner := range owners {
+		names[i] = owner.String()
+	}
+
+	return names
+}
+
+// ownerSpellings remembers the first spelling seen of each owner, so owners that only differ by
+// case are grouped together under one name
+type ownerSpellings map[string]string
+
+func (spellings ownerSpellings) name(owner codeowners.Owner) string {
+	name, found := spellings[owner.Normalized()]
+
+	if !found {
+		name = owner.String()
+		spellings[owner.Normalized()] = name
+	}
+
+	return name
+}
+
+// ownerKey is what owners are compared by, ignoring case like GitHub does. Owners that don't parse are
+// compared as they are written.
+func ownerKey(owner string) string {
+	parsed, err := codeowners.ParseOwner(owner)
+
+	if err != nil {
+		return owner
+	}
+
+	return parsed.Normalized()
+}
+
+func ownerKeys(owners []string) []string {
+	keys := make([]string, len(owners))
+
+	for i, owner := range owners {
+		keys[i] = ownerKey(owner)
+	}
+
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// distinctOwners drops owners listed more than once, ignoring case, keeping the first spelling
+func distinctOwners(owners []string) []string {
+	distinct := []string{}
+	seen := map[string]bool{}
+
+	for _, owner := range owners {
+		key := ownerKey(owner)
+
+		if !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, owner)
+		}
+	}
+
+	return distinct
+}
+
+// changeOwnersFilteredOut reports whether the change has owners, but none of the given kinds
+func changeOwnersFilteredOut(co *codeowners.Codeowners, change Change, kinds []codeowners.OwnerKind) bool {
+	return len(kinds) > 0 && len(changeOwners(co, change, kinds)) == 0 && len(changeOwners(co, change, nil)) > 0
+}
+
+// changeIntentionallyUnowned reports whether every path of the change is matched by a rule without
+// owners, rather than not being matched by any rule.
+func changeIntentionallyUnowned(co *codeowners.Codeowners, change Change) bool {
+	for _, path := range change.Paths() {
+		if !co.IsIntentionallyUnowned([]byte(path)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addChangeFlags adds the flags that choose which changes GetChanges lists, for the commands that look at changes
+func addChangeFlags(cmd *cobra.Command) {
+	fl := cmd.Flags()
+	fl.Bool("staged", false, "Use the files staged for commit instead of unstaged changes")
+	fl.String("base", "", "Use the files changed since the branch forked from the given `ref`, or in the given rev range (e.g. main..HEAD)")
+	fl.Bool("include-untracked", false, "Also include untracked files that aren't ignored")
+	fl.String("files-from", "", "Read the list of files from the given `file`, newline or NUL separated. Use - for stdin")
+	cmd.MarkFlagsMutuallyExclusive("files-from", "staged")
+	cmd.MarkFlagsMutuallyExclusive("files-from", "base")
+	cmd.MarkFlagsMutuallyExclusive("files-from", "include-untracked")
+	// git diff --cached doesn't take a rev range
+	cmd.MarkFlagsMutuallyExclusive("staged", "base")
+}
+
+// GetChanges lists the changes to analyze, by default the unstaged changes in the working tree.
+// The flags from addChangeFlags choose a different set of changes.
+func GetChanges(cmd *cobra.Command, opts *RootCmdOptions) ([]Change, error) {
+	flags := cmd.Flags()
+	filesFrom, _ := flags.GetString("files-from")
+	staged, _ := flags.GetBool("staged")
+	base, _ := flags.GetString("base")
+	includeUntracked, _ := flags.GetBool("include-untracked")
+
+	if filesFrom != "" {
+		files, err := readFileList(cmd, opts, filesFrom)
+
+		if err != nil {
+			return nil, err
+		}
+
+		changes := make([]Change, len(files))
+		for i, file := range files {
+			changes[i] = Change{Status: ChangeModified, Path: file}
+		}
+
+		return changes, nil
+	}
+
+	diffArgs := []string{"--no-pager", "diff", "--name-status", "-M", "-z"}
+
+	if staged {
+		diffArgs = append(diffArgs, "--cached")
+	}
+
+	if base != "" {
+		// A plain ref is compared the way a pull request is, against where the branch forked from it
+		if !strings.Contains(base, "..") {
+			base = base + "...HEAD"
+		}
+
+		diffArgs = append(diffArgs, base)
+	}
+
+	diffOutput, err := opts.GitExec(diffArgs...)
+
+	if err != nil {
+		return nil, fmt.Errorf("error running git diff: %v", err)
+	}
+
+	changes, err := parseNameStatus(diffOutput)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if includeUntracked {
+		root, err := GetRepoRoot(opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Run from the root so the paths are relative to it like the ones from git diff
+		untrackedOutput, err := opts.GitExec("-C", root, "ls-files", "--others", "--exclude-standard", "-z")
+
+		if err != nil {
+			return nil, fmt.Errorf("error finding untracked files: %v", err)
+		}
+
+		for _, file := range splitNul(untrackedOutput) {
+			changes = append(changes, Change{Status: ChangeAdded, Path: file})
+		}
+	}
+
+	return changes, nil
+}
+
+// parseNameStatus parses the output of `git diff --name-status -z`, where each change is a status
+// followed by one path, or two paths for renames and copies, all NUL terminated.
+func parseNameStatus(output []byte) ([]Change, error) {
+	fields := splitNul(output)
+	changes := []Change{}
+
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+
+		if status == "" || i+1 >= len(fields) {
+			return nil, fmt.Errorf("unexpected git diff output, missing path after status '%s'", status)
+		}
+
+		switch status[0] {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("unexpected git diff output, missing new path for '%s'", fields[i+1])
+			}
+
+			if status[0] == 'R' {
+				changes = append(changes, Change{Status: ChangeRenamed, OldPath: fields[i+1], Path: fields[i+2]})
+			} else {
+				// A copy leaves the original alone, only the new file is a change
+				changes = append(changes, Change{Status: ChangeAdded, Path: fields[i+2]})
+			}
+
+			i += 2
+		case 'A':
+			changes = append(changes, Change{Status: ChangeAdded, Path: fields[i+1]})
+			i++
+		case 'D':
+			changes = append(changes, Change{Status: ChangeDeleted, Path: fields[i+1]})
+			i++
+		default:
+			// Modifications, type changes and unmerged files
+			changes = append(changes, Change{Status: ChangeModified, Path: fields[i+1]})
+			i++
+		}
+	}
+
+	return changes, nil
+}
+
+// readFileList reads a newline or NUL separated list of repository relative paths from the given
+// file, or stdin for "-"
+func readFileList(cmd *cobra.Command, opts *RootCmdOptions, filesFrom string) ([]string, error) {
+	var contents []byte
+	var err error
+
+	if filesFrom == "-" {
+		contents, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		var file *File
+		file, err = opts.ReadFile(filesFrom)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not open '%s': %v", filesFrom, err)
+		}
+
+		defer file.Close()
+		contents, err = io.ReadAll(file.Reader)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading file list from '%s': %v", filesFrom, err)
+	}
+
+	// NUL separated lists can hold any path, so only newline separated lists get line endings trimmed
+	if bytes.IndexByte(contents, 0) != -1 {
+		return slices.DeleteFunc(splitNul(contents), func(file string) bool { return file == "" }), nil
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(string(contents), "\n") {
+		file = strings.TrimSuffix(file, "\r")
+
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
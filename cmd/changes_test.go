@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []Change
+		err      string
+	}{
+		{
+			name:     "Empty",
+			output:   "",
+			expected: []Change{},
+		},
+		{
+			name:   "All statuses",
+			output: "M\x00src/main.go\x00A\x00src/new.go\x00D\x00src/old.go\x00R087\x00team-a/file.go\x00team-b/file.go\x00C100\x00a.txt\x00b.txt\x00T\x00link\x00",
+			expected: []Change{
+				{Status: ChangeModified, Path: "src/main.go"},
+				{Status: ChangeAdded, Path: "src/new.go"},
+				{Status: ChangeDeleted, Path: "src/old.go"},
+				{Status: ChangeRenamed, OldPath: "team-a/file.go", Path: "team-b/file.go"},
+				{Status: ChangeAdded, Path: "b.txt"},
+				{Status: ChangeModified, Path: "link"},
+			},
+		},
+		{
+			name:   "Unusual paths",
+			output: "M\x00assets/日本語/ファイル.png\x00A\x00docs/user guide/\"quoted\" name.md\x00R100\x00old\nname.txt\x00new\tname.txt\x00",
+			expected: []Change{
+				{Status: ChangeModified, Path: "assets/日本語/ファイル.png"},
+				{Status: ChangeAdded, Path: "docs/user guide/\"quoted\" name.md"},
+				{Status: ChangeRenamed, OldPath: "old\nname.txt", Path: "new\tname.txt"},
+			},
+		},
+		{
+			name:   "Truncated rename",
+			output: "R100\x00team-a/file.go\x00",
+			err:    "unexpected git diff output, missing new path for 'team-a/file.go'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parseNameStatus([]byte(tt.output))
+
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type ownerFileCount struct {
+	Owner string `json:"owner"`
+	Files int    `json:"files"`
+}
+
+type directoryFileCount struct {
+	Directory string
+	Files     int
+}
+
+func newCmdCoverage(opts *RootCmdOptions) *cobra.Command {
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Report ownership of every tracked file",
+		Long: `Evaluate every file tracked in the repository and show how many files each owner has, how many files are
+unowned and the largest directories that don't have an owner for any of their files.`,
+		Example: "  $ gh codeowners coverage --top 5",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			ownerCounts := map[string]int{}
+			// Owners that only differ by case are counted together
+			countedNames := ownerSpellings{}
+			unownedFiles := []string{}
+			intentionallyUnownedCount := 0
+
+			for _, trackedFile := range trackedFiles {
+				owners := codeowners.FindParsedOwners([]byte(trackedFile))
+
+				if len(owners) == 0 {
+					if codeowners.IsIntentionallyUnowned([]byte(trackedFile)) {
+						intentionallyUnownedCount++
+					} else {
+						unownedFiles = append(unownedFiles, trackedFile)
+					}
+					continue
+				}
+
+				for _, owner := range owners {
+					ownerCounts[countedNames.name(owner)]++
+				}
+			}
+
+			ownedCount := len(trackedFiles) - len(unownedFiles) - intentionallyUnownedCount
+
+			cmd.Printf("Tracked files: %d\n", len(trackedFiles))
+			cmd.Printf("Owned files: %d (%s)\n", ownedCount, percentage(ownedCount, len(trackedFiles)))
+			cmd.Printf("Unowned files: %d (%s)\n", len(unownedFiles), percentage(len(unownedFiles), len(trackedFiles)))
+
+			if intentionallyUnownedCount > 0 {
+				cmd.Printf("Intentionally unowned files: %d (%s)\n", intentionallyUnownedCount, percentage(intentionallyUnownedCount, len(trackedFiles)))
+			}
+
+			if len(ownerCounts) > 0 {
+				cmd.Println()
+				cmd.Println("Files per owner:")
+
+				for _, ownerCount := range sortOwnerCounts(ownerCounts) {
+					cmd.Printf("  %s: %d\n", ownerCount.Owner, ownerCount.Files)
+				}
+			}
+
+			unownedDirectories := largestUnownedDirectories(trackedFiles, unownedFiles)
+
+			if len(unownedDirectories) > 0 && top > 0 {
+				cmd.Println()
+				cmd.Println("Largest unowned directories:")
+
+				for i, directory := range unownedDirectories {
+					if i == top {
+						break
+					}
+
+					cmd.Printf("  %s: %d\n", directory.Directory, directory.Files)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&top, "top", 10, "The number of unowned directories to show, 0 hides them")
+
+	return cmd
+}
+
+func percentage(count int, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+
+	return fmt.Sprintf("%.1f%%", float64(count)*100/float64(total))
+}
+
+// sortOwnerCounts orders owners by the number of files they own, most first, breaking ties by name
+func sortOwnerCounts(ownerCounts map[string]int) []ownerFileCount {
+	sorted := make([]ownerFileCount, 0, len(ownerCounts))
+
+	for owner, files := range ownerCounts {
+		sorted = append(sorted, ownerFileCount{Owner: owner, Files: files})
+	}
+
+	slices.SortFunc(sorted, func(a, b ownerFileCount) int {
+		return cmp.Or(b.Files-a.Files, strings.Compare(a.Owner, b.Owner))
+	})
+
+	return sorted
+}
+
+// largestUnownedDirectories finds the top most directories where none of the files are owned,
+// ordered by how many files they hold, most first.
+func largestUnownedDirectories(trackedFiles []string, unownedFiles []string) []directoryFileCount {
+	totalCounts := map[string]int{}
+	unownedCounts := map[string]int{}
+
+	forEachDirectory := func(file string, action func(directory string)) {
+		for directory := path.Dir(file); ; directory = path.Dir(directory) {
+			action(directory)
+
+			if directory == "." {
+				break
+			}
+		}
+	}
+
+	for _, file := range trackedFiles {
+		forEachDirectory(file, func(directory string) { totalCounts[directory]++ })
+	}
+
+	for _, file := range unownedFiles {
+		forEachDirectory(file, func(directory string) { unownedCounts[directory]++ })
+	}
+
+	directories := []directoryFileCount{}
+
+	for directory, unownedCount := range unownedCounts {
+		if unownedCount != totalCounts[directory] {
+			continue
+		}
+
+		// Only report the top most directory that is completely unowned
+		parent := path.Dir(directory)
+		if directory != "." && unownedCounts[parent] == totalCounts[parent] {
+			continue
+		}
+
+		name := directory + "/"
+		if directory == "." {
+			name = "/"
+		}
+
+		directories = append(directories, directoryFileCount{Directory: name, Files: unownedCount})
+	}
+
+	slices.SortFunc(directories, func(a, b directoryFileCount) int {
+		return cmp.Or(b.Files-a.Files, strings.Compare(a.Directory, b.Directory))
+	})
+
+	return directories
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLargestUnownedDirectories(t *testing.T) {
+	tests := []struct {
+		name     string
+		tracked  []string
+		unowned  []string
+		expected []directoryFileCount
+	}{
+		{
+			name:     "Everything owned",
+			tracked:  []string{"src/main.go", "README.md"},
+			unowned:  []string{},
+			expected: []directoryFileCount{},
+		},
+		{
+			name:    "Top most directory only",
+			tracked: []string{"src/main.go", "tools/a/one.sh", "tools/b/two.sh", "tools/run.sh", "scripts/x/y.sh", "scripts/z.sh", "README.md"},
+			unowned: []string{"tools/a/one.sh", "tools/b/two.sh", "tools/run.sh", "scripts/x/y.sh", "README.md"},
+			expected: []directoryFileCount{
+				{Directory: "tools/", Files: 3},
+				{Directory: "scripts/x/", Files: 1},
+			},
+		},
+		{
+			name:    "Nothing owned",
+			tracked: []string{"src/main.go", "README.md"},
+			unowned: []string{"src/main.go", "README.md"},
+			expected: []directoryFileCount{
+				{Directory: "/", Files: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := largestUnownedDirectories(tt.tracked, tt.unowned)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
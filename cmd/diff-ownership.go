@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+// ownershipChange is a file whose owners are different between two versions of CODEOWNERS
+type ownershipChange struct {
+	Path      string
+	OldOwners []string
+	NewOwners []string
+}
+
+// Gained is true when the file was unowned and now has owners
+func (change *ownershipChange) Gained() bool {
+	return len(change.OldOwners) == 0
+}
+
+// Lost is true when the file had owners and is now unowned
+func (change *ownershipChange) Lost() bool {
+	return len(change.NewOwners) == 0
+}
+
+// ownerDelta counts the files an owner picked up and gave away between two versions of CODEOWNERS
+type ownerDelta struct {
+	Owner  string
+	Gained int
+	Lost   int
+}
+
+func newCmdDiffOwnership(opts *RootCmdOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff-ownership <old-rev> <new-rev>",
+		Short: "Show which files change owners between two versions of CODEOWNERS",
+		Long: `Evaluate every tracked file against the CODEOWNERS file in two revisions and report the files that gained,
+lost or switched owners, followed by how many files each owner gained and lost.`,
+		Example: "  $ gh codeowners diff-ownership main HEAD",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			oldCodeowners, err := GetCodeownersAt(cmd, opts, args[0])
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			newCodeowners, err := GetCodeownersAt(cmd, opts, args[1])
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			changes := diffOwnership(oldCodeowners, newCodeowners, trackedFiles)
+
+			if len(changes) == 0 {
+				cmd.Printf("No files changed owners between %s and %s\n", args[0], args[1])
+				return nil
+			}
+
+			printOwnershipChanges(cmd, changes)
+
+			cmd.Printf("%d of %d files changed owners\n", len(changes), len(trackedFiles))
+			return nil
+		},
+	}
+}
+
+// printOwnershipChanges lists the files that gained, lost and switched owners, then the changes per owner
+func printOwnershipChanges(cmd *cobra.Command, changes []ownershipChange) {
+	printChanges := func(title string, include func(change *ownershipChange) bool, describe func(change *ownershipChange) string) {
+		printedTitle := false
+
+		for i := range changes {
+			if !include(&changes[i]) {
+				continue
+			}
+
+			if !printedTitle {
+				cmd.Println(title)
+				printedTitle = true
+			}
+
+			cmd.Printf("  %s: %s\n", changes[i].Path, describe(&changes[i]))
+		}
+	}
+
+	printChanges("Files that gained owners:",
+		func(change *ownershipChange) bool { return change.Gained() },
+		func(change *ownershipChange) string { return strings.Join(change.NewOwners, " ") })
+
+	printChanges("Files that lost owners:",
+		func(change *ownershipChange) bool { return change.Lost() },
+		func(change *ownershipChange) string { return "was " + strings.Join(change.OldOwners, " ") })
+
+	printChanges("Files that switched owners:",
+		func(change *ownershipChange) bool { return !change.Gained() && !change.Lost() },
+		func(change *ownershipChange) string {
+			return fmt.Sprintf("%s -> %s", strings.Join(change.OldOwners, " "), strings.Join(change.NewOwners, " "))
+		})
+
+	cmd.Println()
+	cmd.Println("Changes per owner:")
+
+	for _, delta := range ownerDeltas(changes) {
+		cmd.Printf("  %s: +%d -%d\n", delta.Owner, delta.Gained, delta.Lost)
+	}
+}
+
+// diffOwnership finds the files whose owners are different in the new CODEOWNERS, in the order they are given.
+// The order owners are listed in doesn't matter, only the set of owners.
+func diffOwnership(oldCodeowners *codeowners.Codeowners, newCodeowners *codeowners.Codeowners, files []string) []ownershipChange {
+	changes := []ownershipChange{}
+
+	for _, file := range files {
+		oldOwners := oldCodeowners.FindOwners([]byte(file))
+		newOwners := newCodeowners.FindOwners([]byte(file))
+
+		if sameOwners(oldOwners, newOwners) {
+			continue
+		}
+
+		changes = append(changes, ownershipChange{Path: file, OldOwners: oldOwners, NewOwners: newOwners})
+	}
+
+	return changes
+}
+
+func sameOwners(a []string, b []string) bool {
+	return slices.Equal(ownerKeys(a), ownerKeys(b))
+}
+
+// ownerDeltas summarizes the changes per owner, ordered by the number of files affected, most first,
+// breaking ties by name. Owners that only differ by case are counted together under the first spelling seen.
+func ownerDeltas(changes []ownershipChange) []ownerDelta {
+	deltas := map[string]*ownerDelta{}
+
+	getDelta := func(owner string) *ownerDelta {
+		key := ownerKey(owner)
+		delta, ok := deltas[key]
+		if !ok {
+			delta = &ownerDelta{Owner: owner}
+			deltas[key] = delta
+		}
+
+		return delta
+	}
+
+	for _, change := range changes {
+		oldKeys := ownerKeys(change.OldOwners)
+		newKeys := ownerKeys(change.NewOwners)
+
+		for _, owner := range distinctOwners(change.NewOwners) {
+			if !slices.Contains(oldKeys, ownerKey(owner)) {
+				getDelta(owner).Gained++
+			}
+		}
+
+		for _, owner := range distinctOwners(change.OldOwners) {
+			if !slices.Contains(newKeys, ownerKey(owner)) {
+				getDelta(owner).Lost++
+			}
+		}
+	}
+
+	sorted := make([]ownerDelta, 0, len(deltas))
+
+	for _, delta := range deltas {
+		sorted = append(sorted, *delta)
+	}
+
+	slices.SortFunc(sorted, func(a, b ownerDelta) int {
+		return cmp.Or((b.Gained+b.Lost)-(a.Gained+a.Lost), strings.Compare(a.Owner, b.Owner))
+	})
+
+	return sorted
+}
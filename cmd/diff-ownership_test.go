@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffOwnership(t *testing.T) {
+	parse := func(lines ...string) *codeowners.Codeowners {
+		co, err := codeowners.FromReader(strings.NewReader(strings.Join(lines, "\n")))
+		assert.NoError(t, err)
+		return co
+	}
+
+	oldCodeowners := parse(
+		"* @default",
+		"/api/ @team-1 @team-2",
+		"/web/ @team-3",
+	)
+
+	newCodeowners := parse(
+		"/api/ @team-2 @team-1",
+		"/web/ @team-4",
+		"/tools/ @team-4",
+	)
+
+	changes := diffOwnership(oldCodeowners, newCodeowners, []string{
+		"api/main.go",
+		"web/index.html",
+		"tools/build.sh",
+		"README.md",
+	})
+
+	assert.Equal(t, []ownershipChange{
+		{Path: "web/index.html", OldOwners: []string{"@team-3"}, NewOwners: []string{"@team-4"}},
+		{Path: "tools/build.sh", OldOwners: []string{"@default"}, NewOwners: []string{"@team-4"}},
+		{Path: "README.md", OldOwners: []string{"@default"}, NewOwners: []string{}},
+	}, changes)
+
+	assert.False(t, changes[0].Gained())
+	assert.False(t, changes[0].Lost())
+	assert.True(t, changes[2].Lost())
+
+	assert.Equal(t, []ownerDelta{
+		{Owner: "@default", Gained: 0, Lost: 2},
+		{Owner: "@team-4", Gained: 2, Lost: 0},
+		{Owner: "@team-3", Gained: 0, Lost: 1},
+	}, ownerDeltas(changes))
+}
+
+func TestDiffOwnership_ignoresCase(t *testing.T) {
+	parse := func(lines ...string) *codeowners.Codeowners {
+		co, err := codeowners.FromReader(strings.NewReader(strings.Join(lines, "\n")))
+		assert.NoError(t, err)
+		return co
+	}
+
+	oldCodeowners := parse(
+		"/api/ @Org/API Docs@Example.com",
+		"/web/ @org/web",
+	)
+
+	newCodeowners := parse(
+		"/api/ @org/api docs@example.com",
+		"/web/ @ORG/WEB @org/design",
+	)
+
+	changes := diffOwnership(oldCodeowners, newCodeowners, []string{
+		"api/main.go",
+		"web/index.html",
+	})
+
+	assert.Equal(t, []ownershipChange{
+		{Path: "web/index.html", OldOwners: []string{"@org/web"}, NewOwners: []string{"@ORG/WEB", "@org/design"}},
+	}, changes)
+
+	assert.Equal(t, []ownerDelta{
+		{Owner: "@org/design", Gained: 1, Lost: 0},
+	}, ownerDeltas(changes))
+}
+
+func TestDiffOwnership_duplicateOwners(t *testing.T) {
+	parse := func(lines ...string) *codeowners.Codeowners {
+		co, err := codeowners.FromReader(strings.NewReader(strings.Join(lines, "\n")))
+		assert.NoError(t, err)
+		return co
+	}
+
+	oldCodeowners := parse("/api/ @org/core @Org/Core")
+	newCodeowners := parse("/api/ @org/api @org/api")
+
+	changes := diffOwnership(oldCodeowners, newCodeowners, []string{
+		"api/main.go",
+		"api/server.go",
+	})
+
+	assert.Len(t, changes, 2)
+
+	assert.Equal(t, []ownerDelta{
+		{Owner: "@org/api", Gained: 2, Lost: 0},
+		{Owner: "@org/core", Gained: 0, Lost: 2},
+	}, ownerDeltas(changes))
+}
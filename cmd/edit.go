@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+// editOptions are the flags shared by the commands that edit the CODEOWNERS file
+type editOptions struct {
+	dryRun bool
+	yes    bool
+}
+
+func (editOpts *editOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&editOpts.dryRun, "dry-run", false, "Show the changes without writing them")
+	cmd.Flags().BoolVarP(&editOpts.yes, "yes", "y", false, "Write the changes without asking first")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "yes")
+}
+
+// editCodeowners applies an edit to the CODEOWNERS file, shows the changed lines and the files that would
+// change owners, then writes the file once the change is confirmed
+func editCodeowners(cmd *cobra.Command, opts *RootCmdOptions, editOpts *editOptions, edit func(document *codeowners.Document) ([]codeowners.DocumentChange, error)) error {
+	file, err := ReadCodeownersFile(cmd, opts)
+
+	if err != nil {
+		return fmt.Errorf("error getting codeowners info: %v", err)
+	}
+
+	return editCodeownersFile(cmd, opts, editOpts, file, edit)
+}
+
+// editCodeownersFile applies an edit to a CODEOWNERS file that was already read, like editCodeowners
+func editCodeownersFile(cmd *cobra.Command, opts *RootCmdOptions, editOpts *editOptions, file *CodeownersFile, edit func(document *codeowners.Document) ([]codeowners.DocumentChange, error)) error {
+	if file.Ref != "" {
+		return fmt.Errorf("can't write a CODEOWNERS file read from --codeowners-ref")
+	}
+
+	document, err := codeowners.ParseDocument(bytes.NewReader(file.Contents), file.Dialect)
+
+	if err != nil {
+		return fmt.Errorf("error parsing '%s': %v", file.Name(), err)
+	}
+
+	current, err := document.Codeowners()
+
+	if err != nil {
+		return fmt.Errorf("error parsing '%s': %v", file.Name(), err)
+	}
+
+	lineChanges, err := edit(document)
+
+	if err != nil {
+		return err
+	}
+
+	if len(lineChanges) == 0 {
+		cmd.Printf("%s already has these owners, nothing to change\n", file.Name())
+		return nil
+	}
+
+	for _, change := range lineChanges {
+		switch {
+		case change.Before == "":
+			cmd.Printf("Adding line %d: %s\n", change.Line, change.After)
+		case change.After == "":
+			cmd.Printf("Removing line %d: %s\n", change.Line, change.Before)
+		default:
+			changing := fmt.Sprintf("Changing line %d", change.Line)
+			cmd.Printf("%s: %s\n", changing, change.Before)
+			cmd.Printf("%*s: %s\n", len(changing), "to", change.After)
+		}
+	}
+
+	cmd.Println()
+
+	proposed, err := document.Codeowners()
+
+	if err != nil {
+		return fmt.Errorf("error parsing the edited '%s': %v", file.Name(), err)
+	}
+
+	trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+	if err != nil {
+		return fmt.Errorf("error getting tracked files: %v", err)
+	}
+
+	ownershipChanges := diffOwnership(current, proposed, trackedFiles)
+
+	if len(ownershipChanges) == 0 {
+		cmd.Println("No files would change owners")
+	} else {
+		printOwnershipChanges(cmd, ownershipChanges)
+		cmd.Printf("%d of %d files would change owners\n", len(ownershipChanges), len(trackedFiles))
+	}
+
+	if editOpts.dryRun {
+		return nil
+	}
+
+	if !editOpts.yes {
+		confirmed, err := opts.Prompter.Confirm(fmt.Sprintf("Write the changes to %s?", file.Name()), true)
+
+		if err != nil {
+			return err
+		}
+
+		if !confirmed {
+			cmd.Println("Nothing was written")
+			return nil
+		}
+	}
+
+	root, err := GetRepoRoot(opts)
+
+	if err != nil {
+		return err
+	}
+
+	if err := opts.WriteFile(filepath.Join(root, file.Path), document.Bytes()); err != nil {
+		return fmt.Errorf("error writing '%s': %v", file.Path, err)
+	}
+
+	cmd.Printf("Wrote %s\n", file.Name())
+	return nil
+}
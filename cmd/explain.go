@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newCmdExplain(opts *RootCmdOptions) *cobra.Command {
+	var showAll bool
+
+	cmd := &cobra.Command{
+		Use:   "explain path...",
+		Short: "Explain who owns a file and why",
+		Long: `Show the CODEOWNERS rule that decides the owners of each given path. Since the last matching rule in
+the CODEOWNERS file wins, use --all to also see the earlier rules that match but are overridden.`,
+		Example: "  $ gh codeowners explain src/main.go docs/README.md",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("at least one path is required")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			root, err := GetRepoRoot(opts)
+
+			if err != nil {
+				return err
+			}
+
+			for _, arg := range args {
+				// Paths are given relative to the current directory but CODEOWNERS matches from the root
+				filePath, err := toRepoPath(opts, root, arg)
+
+				if err != nil {
+					return err
+				}
+
+				cmd.Println(filePath)
+
+				matches := codeowners.MatchAll([]byte(filePath))
+
+				if len(matches) == 0 {
+					cmd.Println("  Not matched by any rule, file is unowned")
+					continue
+				}
+
+				winner := matches[0]
+
+				if len(winner.Owners) == 0 {
+					cmd.Println("  Owners: none, the rule intentionally leaves the file unowned")
+				} else {
+					// A rule can list the same owner more than once, in any case
+					cmd.Printf("  Owners: %s\n", strings.Join(distinctOwners(winner.Owners), " "))
+				}
+
+				cmd.Printf("  Rule: line %d: %s\n", winner.Line, winner.String())
+
+				if showAll && len(matches) > 1 {
+					cmd.Println("  Overridden rules:")
+					for _, match := range matches[1:] {
+						cmd.Printf("    line %d: %s\n", match.Line, match.String())
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Also show the rules that match but are overridden by a later rule")
+
+	return cmd
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdFmt(opts *RootCmdOptions) *cobra.Command {
+	var check bool
+	var write bool
+
+	cmd := &cobra.Command{
+		Use:   "fmt",
+		Short: "Format the CODEOWNERS file",
+		Long: `Rewrite the CODEOWNERS file in a canonical form: owners in lower case without repeats, owners of
+neighbouring rules lined up in the same column and rules sorted by pattern where that can't change
+who owns any file. Comments, blank lines between groups of rules, section headers and the file's line
+endings are kept.
+
+Prints the formatted file unless --write or --check is given. --check exits with a non-zero code
+when the file isn't formatted, so it can be used in CI.`,
+		Example: "  $ gh codeowners fmt --write\n  $ gh codeowners fmt --check",
+		Args:    cobra.NoArgs,
+		// An unformatted file isn't a usage problem
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := ReadCodeownersFile(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			if write && file.Ref != "" {
+				return fmt.Errorf("can't write a CODEOWNERS file read from --codeowners-ref")
+			}
+
+			document, err := codeowners.ParseDocument(bytes.NewReader(file.Contents), file.Dialect)
+
+			if err != nil {
+				return fmt.Errorf("error parsing '%s': %v", file.Name(), err)
+			}
+
+			document.Format()
+			formatted := document.Bytes()
+
+			switch {
+			case check:
+				if !bytes.Equal(formatted, file.Contents) {
+					return fmt.Errorf("%s is not formatted, run 'gh codeowners fmt --write' to format it", file.Name())
+				}
+
+				cmd.Printf("%s is formatted\n", file.Name())
+			case write:
+				if bytes.Equal(formatted, file.Contents) {
+					cmd.Printf("%s is already formatted\n", file.Name())
+					return nil
+				}
+
+				root, err := GetRepoRoot(opts)
+
+				if err != nil {
+					return err
+				}
+
+				if err := opts.WriteFile(filepath.Join(root, file.Path), formatted); err != nil {
+					return fmt.Errorf("error writing '%s': %v", file.Path, err)
+				}
+
+				cmd.Printf("Formatted %s\n", file.Name())
+			default:
+				_, err := cmd.OutOrStdout().Write(formatted)
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Don't write anything, exit with a non-zero code if the file isn't formatted")
+	cmd.Flags().BoolVar(&write, "write", false, "Write the formatted file in place")
+	cmd.MarkFlagsMutuallyExclusive("check", "write")
+
+	return cmd
+}
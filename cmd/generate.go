@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+// Where generate writes the CODEOWNERS file, relative to the root of the repository
+const generatedCodeownersPath = ".github/CODEOWNERS"
+
+const generatedHeader = `# This file is generated by 'gh codeowners generate' from the OWNERS and .owners.yml files in the
+# repository. Don't edit it by hand, edit those files and run 'gh codeowners generate' again.
+`
+
+func newCmdGenerate(opts *RootCmdOptions) *cobra.Command {
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the CODEOWNERS file from OWNERS files next to the code",
+		Long: `Read every OWNERS and .owners.yml file in the repository and write a single .github/CODEOWNERS file with
+the same ownership. A directory is owned by the owners in its OWNERS file along with the owners of the
+directories above it, unless the file says 'set noparent'.
+
+An .owners.yml file declares the same things as an OWNERS file:
+
+  owners: ["@org/backend"]
+  noparent: true
+  include: [/shared/OWNERS]
+  files:
+    - pattern: "*.sql"
+      owners: ["@org/dba"]
+
+With --check nothing is written, and the command exits with a non-zero code when .github/CODEOWNERS isn't
+what would be generated so CI can keep it in sync.`,
+		Example: "  $ gh codeowners generate\n  $ gh codeowners generate --check",
+		Args:    cobra.NoArgs,
+		// An out of date file isn't a usage problem
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ownersFiles, err := ReadOwnersFiles(opts, "")
+
+			if err != nil {
+				return err
+			}
+
+			if len(ownersFiles) == 0 {
+				return fmt.Errorf("could not find any OWNERS or .owners.yml files")
+			}
+
+			generated, diagnostics := codeowners.GenerateCodeowners(ownersFiles, generatedHeader)
+
+			errorCount := 0
+			for _, diagnostic := range diagnostics {
+				if diagnostic.Severity == codeowners.SeverityError {
+					errorCount++
+				}
+
+				if diagnostic.Line == 0 {
+					cmd.PrintErrf("%s: %s: %s\n", diagnostic.Path, diagnostic.Severity, diagnostic.Message)
+				} else {
+					cmd.PrintErrf("%s:%d:%d: %s: %s\n", diagnostic.Path, diagnostic.Line, diagnostic.Column, diagnostic.Severity, diagnostic.Message)
+				}
+			}
+
+			// Leaving out broken lines would quietly drop owners from the generated file
+			if errorCount > 0 {
+				return fmt.Errorf("found %d errors in the OWNERS files", errorCount)
+			}
+
+			root, err := GetRepoRoot(opts)
+
+			if err != nil {
+				return err
+			}
+
+			// A missing file is out of date like any other
+			current, _ := readRepoFile(opts, root, "", generatedCodeownersPath)
+
+			if bytes.Equal(current, generated) {
+				cmd.Printf("%s is up to date\n", generatedCodeownersPath)
+				return nil
+			}
+
+			if check {
+				return fmt.Errorf("%s is out of date, run 'gh codeowners generate' to update it", generatedCodeownersPath)
+			}
+
+			if err := opts.WriteFile(filepath.Join(root, generatedCodeownersPath), generated); err != nil {
+				return fmt.Errorf("error writing '%s': %v", generatedCodeownersPath, err)
+			}
+
+			cmd.Printf("Wrote %s from %d OWNERS files\n", generatedCodeownersPath, len(ownersFiles))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Don't write anything, exit with a non-zero code if the CODEOWNERS file is out of date")
+
+	return cmd
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the mapping from commit authors to teams is read from, relative to the root of the repository
+const defaultTeamsPath = ".github/codeowners-teams.yml"
+
+// historyCommit is a commit from git log with the files it changed
+type historyCommit struct {
+	Time  time.Time
+	Email string
+	Name  string
+	Files []historyFile
+}
+
+// historyFile is a file changed in a commit
+type historyFile struct {
+	Path string
+	// Lines added and removed, binary files count as a single line
+	Lines int
+}
+
+// readHistory reads the commits since the given date, which is anything git log --since accepts. Merge
+// commits are skipped and renamed files are reported by their new path.
+func readHistory(opts *RootCmdOptions, root string, since string) ([]historyCommit, error) {
+	logOutput, err := opts.GitExec("-C", root, "log", "--no-merges", "--no-renames", "--numstat", "-z", "--format=%x1e%at%x1f%aE%x1f%aN", "--since="+since)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading git log: %v", err)
+	}
+
+	commits := []historyCommit{}
+
+	for _, record := range strings.Split(string(logOutput), "\x1e")[1:] {
+		header, numstat, _ := strings.Cut(record, "\x00")
+		fields := strings.Split(header, "\x1f")
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git log output '%s'", header)
+		}
+
+		timestamp, err := strconv.ParseInt(fields[0], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("unexpected git log output '%s'", header)
+		}
+
+		commit := historyCommit{Time: time.Unix(timestamp, 0), Email: fields[1], Name: fields[2]}
+
+		for _, change := range splitNul([]byte(strings.TrimPrefix(numstat, "\n"))) {
+			added, rest, _ := strings.Cut(change, "\t")
+			removed, changedPath, found := strings.Cut(rest, "\t")
+
+			if !found {
+				continue
+			}
+
+			addedLines, addedErr := strconv.Atoi(added)
+			removedLines, removedErr := strconv.Atoi(removed)
+
+			lines := addedLines + removedLines
+			if addedErr != nil || removedErr != nil {
+				lines = 1
+			}
+
+			commit.Files = append(commit.Files, historyFile{Path: changedPath, Lines: lines})
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// noreplyEmailRE matches the private email addresses GitHub commits with, which include the login of the author
+var noreplyEmailRE = regexp.MustCompile(`^(?:\d+\+)?([A-Za-z0-9-]+)@users\.noreply\.github\.com$`)
+
+// authorTeams maps commit authors, by email or name, to the teams they're on
+type authorTeams struct {
+	teams map[string][]string
+}
+
+// readAuthorTeams reads the mapping of teams to their members:
+//
+//	"@org/payments":
+//	  - alice@example.com
+//	  - Bob Smith
+//
+// The file given with --teams is read relative to the current directory, otherwise .github/codeowners-teams.yml
+// is read from the root of the repository if there is one.
+func readAuthorTeams(opts *RootCmdOptions, root string, teamsPath string) (*authorTeams, error) {
+	var contents []byte
+	var err error
+
+	if teamsPath != "" {
+		contents, err = readAllFile(opts, teamsPath)
+	} else {
+		teamsPath = defaultTeamsPath
+		contents, err = readRepoFile(opts, root, "", defaultTeamsPath)
+
+		if err != nil {
+			return &authorTeams{teams: map[string][]string{}}, nil
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading '%s': %v", filepath.ToSlash(teamsPath), err)
+	}
+
+	members := map[string][]string{}
+
+	if err := yaml.NewDecoder(bytes.NewReader(contents)).Decode(&members); err != nil && len(bytes.TrimSpace(contents)) > 0 {
+		return nil, fmt.Errorf("error parsing '%s', expected a list of members for each team: %v", filepath.ToSlash(teamsPath), err)
+	}
+
+	teams := &authorTeams{teams: map[string][]string{}}
+
+	for team, teamMembers := range members {
+		if _, err := codeowners.ParseOwner(team); err != nil {
+			return nil, fmt.Errorf("error parsing '%s': %v", filepath.ToSlash(teamsPath), err)
+		}
+
+		for _, member := range teamMembers {
+			key := strings.ToLower(member)
+
+			if !slices.Contains(teams.teams[key], team) {
+				teams.teams[key] = append(teams.teams[key], team)
+			}
+		}
+	}
+
+	return teams, nil
+}
+
+// ownersOf returns the owners a commit author counts as: the teams they're on, otherwise themselves
+func (teams *authorTeams) ownersOf(commit historyCommit) []string {
+	if owners := teams.teamsOf(commit); len(owners) > 0 {
+		return owners
+	}
+
+	if identity, ok := authorIdentity(commit); ok {
+		return []string{identity}
+	}
+
+	return []string{}
+}
+
+// teamsOf returns the teams the author of a commit is on
+func (teams *authorTeams) teamsOf(commit historyCommit) []string {
+	owners := []string{}
+
+	for _, key := range []string{strings.ToLower(commit.Email), strings.ToLower(commit.Name)} {
+		for _, team := range teams.teams[key] {
+			if !slices.Contains(owners, team) {
+				owners = append(owners, team)
+			}
+		}
+	}
+
+	return owners
+}
+
+// authorIdentity returns the author of a commit as an owner: their GitHub login when they commit with a
+// noreply address, otherwise their email
+func authorIdentity(commit historyCommit) (string, bool) {
+	if match := noreplyEmailRE.FindStringSubmatch(commit.Email); match != nil {
+		return "@" + match[1], true
+	}
+
+	if _, err := codeowners.ParseOwner(commit.Email); err == nil {
+		return commit.Email, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdLint(opts *RootCmdOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint",
+		Short: "Check the CODEOWNERS file for problems",
+		Long: `Check the CODEOWNERS file for patterns GitHub can't use, syntax GitHub ignores, malformed owners,
+duplicate patterns, a file over GitHub's size limit and a CODEOWNERS file that doesn't own itself.
+Exits with a non-zero code when any errors are found so it can be used to gate changes in CI.`,
+		Example: "  $ gh codeowners lint",
+		Args:    cobra.NoArgs,
+		// Problems in the file aren't usage problems
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := ReadCodeownersFile(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			location := file.Name()
+
+			parsedCodeowners, err := ParseCodeownersFile(cmd, opts, file)
+
+			if err != nil {
+				return fmt.Errorf("error parsing '%s': %v", location, err)
+			}
+
+			lintOptions := codeowners.LintOptions{
+				Path: file.Path,
+				Size: len(file.Contents),
+			}
+
+			// The size limit and owning itself are about GitHub's CODEOWNERS file
+			if file.Dialect == codeowners.DialectGerrit {
+				lintOptions = codeowners.LintOptions{}
+			}
+
+			diagnostics := codeowners.Lint(parsedCodeowners, lintOptions)
+
+			errorCount := 0
+			for _, diagnostic := range diagnostics {
+				if diagnostic.Severity == codeowners.SeverityError {
+					errorCount++
+				}
+
+				if diagnostic.Line == 0 {
+					cmd.Printf("%s: %s: %s\n", file.diagnosticLocation(diagnostic), diagnostic.Severity, diagnostic.Message)
+				} else {
+					cmd.Printf("%s:%d:%d: %s: %s\n", file.diagnosticLocation(diagnostic), diagnostic.Line, diagnostic.Column, diagnostic.Severity, diagnostic.Message)
+				}
+			}
+
+			if len(diagnostics) == 0 {
+				cmd.Printf("%s: no problems found\n", location)
+			}
+
+			if errorCount > 0 {
+				return fmt.Errorf("found %d errors in %s", errorCount, location)
+			}
+
+			return nil
+		},
+	}
+}
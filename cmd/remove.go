@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdRemove(opts *RootCmdOptions) *cobra.Command {
+	editOpts := &editOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "remove <pattern>",
+		Short: "Remove the rules for a pattern from the CODEOWNERS file",
+		Long: `Remove every rule for the pattern, written exactly as it is in the CODEOWNERS file. Files matched by
+the pattern fall back to the owners of the rules above it.
+
+The changed lines and the files that would change owners are shown before anything is written.`,
+		Example: "  $ gh codeowners remove /services/billing/",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editCodeowners(cmd, opts, editOpts, func(document *codeowners.Document) ([]codeowners.DocumentChange, error) {
+				changes := document.RemoveRules(args[0])
+
+				if len(changes) == 0 {
+					return nil, fmt.Errorf("there is no rule for '%s'", args[0])
+				}
+
+				return changes, nil
+			})
+		},
+	}
+
+	editOpts.addFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdRenameTeam(opts *RootCmdOptions) *cobra.Command {
+	editOpts := &editOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rename-team <@org/team> <new-name>",
+		Short: "Rename a team everywhere it is listed in the CODEOWNERS file",
+		Long: `Rename a team in the CODEOWNERS file after it was renamed on GitHub. The new name can be just the team
+slug, which keeps the team in the same organization, or a full @org/team.
+
+The changed lines and the files that would change owners are shown before anything is written.`,
+		Example: `  $ gh codeowners rename-team @org/payments billing
+  $ gh codeowners rename-team @org/payments @other-org/billing`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := codeowners.ParseOwner(args[0])
+
+			if err != nil {
+				return err
+			}
+
+			if from.Kind != codeowners.OwnerTeam {
+				return fmt.Errorf("'%s' is not a team, use transfer to replace users and emails", args[0])
+			}
+
+			newName := args[1]
+			if !strings.HasPrefix(newName, "@") {
+				newName = fmt.Sprintf("@%s/%s", from.Org, newName)
+			}
+
+			to, err := codeowners.ParseOwner(newName)
+
+			if err != nil {
+				return err
+			}
+
+			if to.Kind != codeowners.OwnerTeam {
+				return fmt.Errorf("'%s' is not a team", args[1])
+			}
+
+			if from.Equal(to) {
+				return fmt.Errorf("'%s' is already called '%s'", args[0], args[1])
+			}
+
+			return editCodeowners(cmd, opts, editOpts, func(document *codeowners.Document) ([]codeowners.DocumentChange, error) {
+				changes := document.ReplaceOwner(from, to)
+
+				if len(changes) == 0 {
+					return nil, fmt.Errorf("'%s' isn't listed in the CODEOWNERS file", args[0])
+				}
+
+				return changes, nil
+			})
+		},
+	}
+
+	editOpts.addFlags(cmd)
+
+	return cmd
+}
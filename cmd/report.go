@@ -1,67 +1,315 @@
 package cmd
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/justindbaur/gh-codeowners/codeowners"
 	"github.com/spf13/cobra"
 )
 
+var reportFormats = []string{"table", "json", "csv", "markdown"}
+
+type reportRule struct {
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+}
+
+type reportFile struct {
+	Path    string       `json:"path"`
+	OldPath string       `json:"oldPath,omitempty"`
+	Status  ChangeStatus `json:"status"`
+	// Owners of every path touched, for renames that's the owners of both the old and new path
+	Owners  []string    `json:"owners"`
+	Rule    *reportRule `json:"rule"`
+	OldRule *reportRule `json:"oldRule,omitempty"`
+}
+
+type report struct {
+	Files []reportFile `json:"files"`
+	// Counts of files that have a single owner
+	Owners []ownerFileCount `json:"owners"`
+	// Files that aren't matched by any rule
+	UnownedFiles int `json:"unownedFiles"`
+	// Files matched by a rule without owners
+	IntentionallyUnownedFiles int `json:"intentionallyUnownedFiles"`
+	// Files that have owners, but none of the kinds chosen with --owner-kind
+	FilteredOwnerFiles int          `json:"filteredOwnerFiles,omitempty"`
+	MultipleOwnerFiles []reportFile `json:"multipleOwnerFiles"`
+	// Counts of files per owner within each section, only for GitLab files with sections
+	Sections []reportSection `json:"sections,omitempty"`
+}
+
+type reportSection struct {
+	Name      string           `json:"name"`
+	Optional  bool             `json:"optional"`
+	Approvals int              `json:"approvals"`
+	Owners    []ownerFileCount `json:"owners"`
+}
+
+// describe shows the name of the section with how its approvals work
+func (section *reportSection) describe() string {
+	details := []string{}
+
+	if section.Optional {
+		details = append(details, "optional")
+	}
+
+	if section.Approvals > 1 {
+		details = append(details, fmt.Sprintf("%d approvals", section.Approvals))
+	}
+
+	if len(details) == 0 {
+		return section.Name
+	}
+
+	return fmt.Sprintf("%s (%s)", section.Name, strings.Join(details, ", "))
+}
+
+func (file *reportFile) displayPath() string {
+	return Change{Path: file.Path, OldPath: file.OldPath}.String()
+}
+
 func newCmdReport(opts *RootCmdOptions) *cobra.Command {
-	return &cobra.Command{
+	var format string
+
+	cmd := &cobra.Command{
 		Use:     "report",
 		Short:   "Report on current working directory",
 		Long:    "Show report of the owners of all files in the current working directory",
-		Example: "  $ gh codeowners report",
+		Example: "  $ gh codeowners report\n  $ gh codeowners report --format json",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			edittedFilesScanner, err := GetEdittedFilesScanner(cmd, opts)
+			if !slices.Contains(reportFormats, format) {
+				return fmt.Errorf("invalid format '%s', expected one of %s", format, strings.Join(reportFormats, ", "))
+			}
+
+			kinds, err := GetOwnerKinds(cmd)
 
 			if err != nil {
-				return fmt.Errorf("error getting editted files scanner: %v", err)
+				return err
 			}
 
-			codeowners, err := GetCodeowners(cmd, opts)
+			changes, err := GetChanges(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting changed files: %v", err)
+			}
+
+			co, err := GetCodeowners(cmd, opts)
 
 			if err != nil {
 				return fmt.Errorf("error getting codeowners info: %v", err)
 			}
 
-			singleOwnerReport := map[string]int{}
+			ownerReport := &report{
+				Files:              []reportFile{},
+				MultipleOwnerFiles: []reportFile{},
+			}
+			singleOwnerCounts := map[string]int{}
+			sectionOwnerCounts := map[*codeowners.Section]map[string]int{}
+			// Owners that only differ by case are counted together
+			countedNames := ownerSpellings{}
+			hasSections := len(co.Sections()) > 1
 
-			// Loop over all editted files
-			for edittedFilesScanner.Scan() {
-				owners := codeowners.FindOwners(edittedFilesScanner.Bytes())
-				if len(owners) == 1 {
-					owner := owners[0]
-					existingValue, found := singleOwnerReport[owner]
+			// Loop over all changed files
+			for _, change := range changes {
+				owners := changeOwners(co, change, kinds)
 
-					if found {
-						singleOwnerReport[owner] = existingValue + 1
-					} else {
-						singleOwnerReport[owner] = 1
+				file := reportFile{
+					Path:    change.Path,
+					OldPath: change.OldPath,
+					Status:  change.Status,
+					Owners:  ownerNames(owners),
+				}
+
+				if entry := co.Match([]byte(change.Path)); entry != nil {
+					file.Rule = &reportRule{Line: entry.Line, Pattern: entry.Pattern}
+				}
+
+				if change.OldPath != "" {
+					if entry := co.Match([]byte(change.OldPath)); entry != nil {
+						file.OldRule = &reportRule{Line: entry.Line, Pattern: entry.Pattern}
 					}
-				} else if len(owners) > 1 {
-					cmd.Printf("File '%s' is owned by multiple teams %s\n", edittedFilesScanner.Text(), strings.Join(owners, ", "))
-				} else {
-					// TODO: Could do something about unowned files here
-					existingValue, found := singleOwnerReport[""]
+				}
 
-					if found {
-						singleOwnerReport[""] = existingValue + 1
-					} else {
-						singleOwnerReport[""] = 1
+				ownerReport.Files = append(ownerReport.Files, file)
+
+				if hasSections {
+					// Every section requires its own approval, so every owner counts in its section
+					for section, sectionOwners := range changeSectionOwners(co, change, kinds) {
+						if sectionOwnerCounts[section] == nil {
+							sectionOwnerCounts[section] = map[string]int{}
+						}
+
+						for _, owner := range sectionOwners {
+							sectionOwnerCounts[section][countedNames.name(owner)]++
+						}
 					}
 				}
-			}
 
-			for owner, ownedFiles := range singleOwnerReport {
-				if owner == "" {
-					cmd.Printf("Files that are unowned: %d\n", ownedFiles)
+				if len(owners) == 1 {
+					singleOwnerCounts[countedNames.name(owners[0])]++
+				} else if len(file.Owners) > 1 {
+					ownerReport.MultipleOwnerFiles = append(ownerReport.MultipleOwnerFiles, file)
+				} else if changeOwnersFilteredOut(co, change, kinds) {
+					ownerReport.FilteredOwnerFiles++
+				} else if changeIntentionallyUnowned(co, change) {
+					ownerReport.IntentionallyUnownedFiles++
 				} else {
-					cmd.Printf("%s: %d\n", owner, ownedFiles)
+					ownerReport.UnownedFiles++
 				}
 			}
+
+			sortByPath := func(a, b reportFile) int {
+				return strings.Compare(a.Path, b.Path)
+			}
+
+			slices.SortFunc(ownerReport.Files, sortByPath)
+			slices.SortFunc(ownerReport.MultipleOwnerFiles, sortByPath)
+			ownerReport.Owners = sortOwnerCounts(singleOwnerCounts)
+
+			for _, section := range co.Sections() {
+				if counts, found := sectionOwnerCounts[section]; found {
+					ownerReport.Sections = append(ownerReport.Sections, reportSection{
+						Name:      sectionName(section),
+						Optional:  section.Optional,
+						Approvals: section.Approvals,
+						Owners:    sortOwnerCounts(counts),
+					})
+				}
+			}
+
+			switch format {
+			case "json":
+				return writeReportJSON(cmd, ownerReport)
+			case "csv":
+				return writeReportCSV(cmd, ownerReport)
+			case "markdown":
+				writeReportMarkdown(cmd, ownerReport)
+			default:
+				writeReportTable(cmd, ownerReport)
+			}
+
 			return nil
 		},
 	}
+
+	addChangeFlags(cmd)
+	addOwnerKindFlag(cmd)
+	cmd.Flags().StringVar(&format, "format", "table", fmt.Sprintf("The output format: {%s}", strings.Join(reportFormats, "|")))
+
+	return cmd
+}
+
+func writeReportTable(cmd *cobra.Command, ownerReport *report) {
+	for _, file := range ownerReport.MultipleOwnerFiles {
+		cmd.Printf("File '%s' is owned by multiple teams %s\n", file.displayPath(), strings.Join(file.Owners, ", "))
+	}
+
+	for _, ownerCount := range ownerReport.Owners {
+		cmd.Printf("%s: %d\n", ownerCount.Owner, ownerCount.Files)
+	}
+
+	if ownerReport.UnownedFiles > 0 {
+		cmd.Printf("Files that are unowned: %d\n", ownerReport.UnownedFiles)
+	}
+
+	if ownerReport.IntentionallyUnownedFiles > 0 {
+		cmd.Printf("Files that are intentionally unowned: %d\n", ownerReport.IntentionallyUnownedFiles)
+	}
+
+	if ownerReport.FilteredOwnerFiles > 0 {
+		cmd.Printf("Files without owners of the chosen kinds: %d\n", ownerReport.FilteredOwnerFiles)
+	}
+
+	for _, section := range ownerReport.Sections {
+		cmd.Printf("[%s]\n", section.describe())
+
+		for _, ownerCount := range section.Owners {
+			cmd.Printf("  %s: %d\n", ownerCount.Owner, ownerCount.Files)
+		}
+	}
+}
+
+func writeReportJSON(cmd *cobra.Command, ownerReport *report) error {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ownerReport)
+}
+
+func writeReportCSV(cmd *cobra.Command, ownerReport *report) error {
+	writer := csv.NewWriter(cmd.OutOrStdout())
+
+	if err := writer.Write([]string{"path", "old_path", "status", "owners", "rule_line", "rule_pattern", "old_rule_line", "old_rule_pattern"}); err != nil {
+		return err
+	}
+
+	ruleColumns := func(rule *reportRule) (string, string) {
+		if rule == nil {
+			return "", ""
+		}
+
+		return fmt.Sprint(rule.Line), rule.Pattern
+	}
+
+	for _, file := range ownerReport.Files {
+		line, pattern := ruleColumns(file.Rule)
+		oldLine, oldPattern := ruleColumns(file.OldRule)
+
+		if err := writer.Write([]string{file.Path, file.OldPath, string(file.Status), strings.Join(file.Owners, " "), line, pattern, oldLine, oldPattern}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeReportMarkdown(cmd *cobra.Command, ownerReport *report) {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ").Replace
+
+	cmd.Println("| Owner | Files |")
+	cmd.Println("| --- | --- |")
+
+	for _, ownerCount := range ownerReport.Owners {
+		cmd.Printf("| %s | %d |\n", escape(ownerCount.Owner), ownerCount.Files)
+	}
+
+	cmd.Printf("| Multiple owners | %d |\n", len(ownerReport.MultipleOwnerFiles))
+	cmd.Printf("| Unowned | %d |\n", ownerReport.UnownedFiles)
+	cmd.Printf("| Intentionally unowned | %d |\n", ownerReport.IntentionallyUnownedFiles)
+
+	// Only possible with --owner-kind
+	if ownerReport.FilteredOwnerFiles > 0 {
+		cmd.Printf("| Without owners of the chosen kinds | %d |\n", ownerReport.FilteredOwnerFiles)
+	}
+
+	if len(ownerReport.MultipleOwnerFiles) > 0 {
+		cmd.Println()
+		cmd.Println("### Files with multiple owners")
+		cmd.Println()
+		cmd.Println("| File | Owners |")
+		cmd.Println("| --- | --- |")
+
+		for _, file := range ownerReport.MultipleOwnerFiles {
+			cmd.Printf("| `%s` | %s |\n", escape(file.displayPath()), escape(strings.Join(file.Owners, " ")))
+		}
+	}
+
+	if len(ownerReport.Sections) > 0 {
+		cmd.Println()
+		cmd.Println("### Sections")
+		cmd.Println()
+		cmd.Println("| Section | Owner | Files |")
+		cmd.Println("| --- | --- | --- |")
+
+		for _, section := range ownerReport.Sections {
+			for _, ownerCount := range section.Owners {
+				cmd.Printf("| %s | %s | %d |\n", escape(section.describe()), escape(ownerCount.Owner), ownerCount.Files)
+			}
+		}
+	}
 }
@@ -23,6 +23,7 @@ type RootCmdOptions struct {
 	Out           io.Writer
 	Err           io.Writer
 	ReadFile      func(filePath string) (*File, error)
+	WriteFile     func(filePath string, contents []byte) error
 	GitExec       func(arg ...string) ([]byte, error)
 	GhExec        func(arg ...string) (stdout bytes.Buffer, stderr bytes.Buffer, err error)
 	Prompter      Prompter
@@ -39,13 +40,30 @@ func NewCmdRoot(opts *RootCmdOptions) *cobra.Command {
 	rootCmd.SetOut(opts.Out)
 	rootCmd.SetErr(opts.Err)
 
-	// TODO: Add persistent flag for giving us the location of the codeowners file
-	// TODO: Add persistent flag for giving us the list of files to use
-	rootCmd.PersistentFlags().Bool("help", false, "Show help for command")
+	pf := rootCmd.PersistentFlags()
+	pf.Bool("help", false, "Show help for command")
+	pf.String("codeowners", "", "Use the given CODEOWNERS `file` instead of finding the one GitHub would use")
+	pf.String("codeowners-ref", "", "Read the CODEOWNERS file as it is in the given `revision` instead of the working tree")
+	pf.String("dialect", "auto", "The CODEOWNERS `dialect`: {github|gitlab|bitbucket|gerrit|auto}, auto treats files with [Section] headers as gitlab, @@@group definitions as bitbucket and a root OWNERS file as gerrit")
 
 	rootCmd.AddCommand(newCmdReport(opts))
 	rootCmd.AddCommand(newCmdStage(opts))
 	rootCmd.AddCommand(newCmdAutoPR(opts))
+	rootCmd.AddCommand(newCmdExplain(opts))
+	rootCmd.AddCommand(newCmdLint(opts))
+	rootCmd.AddCommand(newCmdUnused(opts))
+	rootCmd.AddCommand(newCmdCoverage(opts))
+	rootCmd.AddCommand(newCmdDiffOwnership(opts))
+	rootCmd.AddCommand(newCmdSimulate(opts))
+	rootCmd.AddCommand(newCmdGenerate(opts))
+	rootCmd.AddCommand(newCmdFmt(opts))
+	rootCmd.AddCommand(newCmdAdd(opts))
+	rootCmd.AddCommand(newCmdRemove(opts))
+	rootCmd.AddCommand(newCmdTransfer(opts))
+	rootCmd.AddCommand(newCmdRenameTeam(opts))
+	rootCmd.AddCommand(newCmdSuggest(opts))
+	rootCmd.AddCommand(newCmdStale(opts))
+	rootCmd.AddCommand(newCmdTest(opts))
 
 	return rootCmd
 }
@@ -1,41 +1,408 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/justindbaur/gh-codeowners/codeowners"
 	"github.com/spf13/cobra"
 )
 
-var possibleCodeownersLocations = [3]string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+// The locations GitHub checks for a CODEOWNERS file, in the order it checks them
+var possibleCodeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
 
-func GetCodeowners(cmd *cobra.Command, opts *RootCmdOptions) (*codeowners.Codeowners, error) {
-	// TODO: Use flag maybe
-	for _, location := range possibleCodeownersLocations {
-		file, err := opts.ReadFile(location)
+// The locations GitLab checks for a CODEOWNERS file, in the order it checks them
+var possibleGitLabCodeownersLocations = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// The locations Bitbucket checks for a CODEOWNERS file, in the order it checks them
+var possibleBitbucketCodeownersLocations = []string{"CODEOWNERS", ".bitbucket/CODEOWNERS"}
+
+// Gerrit has an OWNERS file in each directory, the one at the root owns the whole repository
+var possibleGerritOwnersLocations = []string{"OWNERS"}
+
+// Locations only one host checks, looked at when none of GitHub's locations have a file
+var hostOnlyLocations = []struct {
+	locations []string
+	dialect   codeowners.Dialect
+}{
+	{[]string{".gitlab/CODEOWNERS"}, codeowners.DialectGitLab},
+	{[]string{".bitbucket/CODEOWNERS"}, codeowners.DialectBitbucket},
+	{possibleGerritOwnersLocations, codeowners.DialectGerrit},
+}
+
+// CodeownersFile is the CODEOWNERS file being used, before it's parsed
+type CodeownersFile struct {
+	// Path relative to the root of the repository
+	Path string
+	// Revision the file was read from, empty for the working tree
+	Ref      string
+	Contents []byte
+	// Dialect given with --dialect, or detected from the file
+	Dialect codeowners.Dialect
+}
+
+// Name identifies the file in messages, including the revision it came from
+func (file *CodeownersFile) Name() string {
+	if file.Ref != "" {
+		return fmt.Sprintf("%s:%s", file.Ref, file.Path)
+	}
+
+	return file.Path
+}
+
+// diagnosticLocation identifies the file a diagnostic is in, which is another OWNERS file for Gerrit
+func (file *CodeownersFile) diagnosticLocation(diagnostic codeowners.Diagnostic) string {
+	if diagnostic.Path == "" {
+		return file.Name()
+	}
+
+	located := CodeownersFile{Path: diagnostic.Path, Ref: file.Ref}
+	return located.Name()
+}
+
+// GetRepoRoot returns the top level directory of the repository, or of the worktree when run in a linked worktree
+func GetRepoRoot(opts *RootCmdOptions) (string, error) {
+	topLevelDirBytes, err := opts.GitExec("rev-parse", "--show-toplevel")
+
+	if err != nil {
+		return "", fmt.Errorf("could not find top level dir: %v", err)
+	}
+
+	return strings.TrimSuffix(string(topLevelDirBytes), "\n"), nil
+}
+
+// toRepoPath converts a path given on the command line, which is relative to the current directory,
+// into a path relative to the root of the repository like the paths git reports.
+func toRepoPath(opts *RootCmdOptions, root string, filePath string) (string, error) {
+	if filepath.IsAbs(filePath) {
+		relativePath, err := filepath.Rel(root, filePath)
+
+		if err != nil {
+			return "", fmt.Errorf("'%s' is not in the repository: %v", filePath, err)
+		}
+
+		return filepath.ToSlash(relativePath), nil
+	}
+
+	prefixBytes, err := opts.GitExec("rev-parse", "--show-prefix")
+
+	if err != nil {
+		return "", fmt.Errorf("could not find current directory in the repository: %v", err)
+	}
+
+	prefix := strings.TrimSuffix(string(prefixBytes), "\n")
+
+	return path.Join(prefix, filepath.ToSlash(filePath)), nil
+}
+
+// readRepoFile reads a file by its path relative to the root of the repository, as it is in the given
+// revision or in the working tree when ref is empty
+func readRepoFile(opts *RootCmdOptions, root string, ref string, repoPath string) ([]byte, error) {
+	if ref == "" {
+		return readAllFile(opts, filepath.Join(root, repoPath))
+	}
+
+	// Paths in <rev>:<path> are relative to the root of the repository
+	return opts.GitExec("show", fmt.Sprintf("%s:%s", ref, repoPath))
+}
+
+func readAllFile(opts *RootCmdOptions, filePath string) ([]byte, error) {
+	file, err := opts.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file.Reader)
+}
+
+// ReadCodeownersFile reads the CODEOWNERS file chosen by the --codeowners and --codeowners-ref flags
+func ReadCodeownersFile(cmd *cobra.Command, opts *RootCmdOptions) (*CodeownersFile, error) {
+	ref, _ := cmd.Flags().GetString("codeowners-ref")
+	return ReadCodeownersFileAt(cmd, opts, ref)
+}
+
+// ReadCodeownersFileAt reads the file given with --codeowners, or otherwise finds the CODEOWNERS file
+// that GitHub would use from the root of the repository. The file is read as it is in the given
+// revision, or from the working tree when ref is empty.
+func ReadCodeownersFileAt(cmd *cobra.Command, opts *RootCmdOptions, ref string) (*CodeownersFile, error) {
+	dialectName, _ := cmd.Flags().GetString("dialect")
+	detectDialect := dialectName == "" || dialectName == "auto"
+
+	var dialect codeowners.Dialect
+	if !detectDialect {
+		parsedDialect, err := codeowners.ParseDialect(dialectName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dialect = parsedDialect
+	}
+
+	root, err := GetRepoRoot(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newFile := func(repoPath string, contents []byte) *CodeownersFile {
+		file := &CodeownersFile{Path: repoPath, Ref: ref, Contents: contents, Dialect: dialect}
+
+		if detectDialect {
+			switch {
+			case strings.HasPrefix(repoPath, ".gitlab/"):
+				file.Dialect = codeowners.DialectGitLab
+			case strings.HasPrefix(repoPath, ".bitbucket/"):
+				file.Dialect = codeowners.DialectBitbucket
+			case path.Base(repoPath) == "OWNERS":
+				file.Dialect = codeowners.DialectGerrit
+			default:
+				file.Dialect = codeowners.DetectDialect(contents)
+			}
+		}
+
+		return file
+	}
+
+	if flagPath, _ := cmd.Flags().GetString("codeowners"); flagPath != "" {
+		repoPath, err := toRepoPath(opts, root, flagPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var contents []byte
+		if ref == "" {
+			// Read the path as given, it may not even be in the repository
+			contents, err = readAllFile(opts, flagPath)
+		} else {
+			contents, err = readRepoFile(opts, root, ref, repoPath)
+		}
 
 		if err != nil {
-			// Not found in that location, try the other ones
+			return nil, fmt.Errorf("error reading '%s': %v", flagPath, err)
+		}
+
+		return newFile(repoPath, contents), nil
+	}
+
+	locations := possibleCodeownersLocations
+	host := "GitHub"
+
+	if !detectDialect {
+		switch dialect {
+		case codeowners.DialectGitLab:
+			locations = possibleGitLabCodeownersLocations
+			host = "GitLab"
+		case codeowners.DialectBitbucket:
+			locations = possibleBitbucketCodeownersLocations
+			host = "Bitbucket"
+		case codeowners.DialectGerrit:
+			locations = possibleGerritOwnersLocations
+			host = "Gerrit"
+		}
+	}
+
+	findFiles := func(locations []string) []*CodeownersFile {
+		found := []*CodeownersFile{}
+
+		for _, location := range locations {
+			contents, err := readRepoFile(opts, root, ref, location)
+
+			if err != nil {
+				// Not found in that location, try the other ones
+				continue
+			}
+
+			found = append(found, newFile(location, contents))
+		}
+
+		return found
+	}
+
+	found := findFiles(locations)
+
+	if detectDialect {
+		for _, hostOnly := range hostOnlyLocations {
+			if len(found) > 0 {
+				break
+			}
+
+			found = findFiles(hostOnly.locations)
+		}
+	}
+
+	if len(found) == 0 {
+		if ref != "" {
+			return nil, fmt.Errorf("could not locate a CODEOWNERS file in '%s'", ref)
+		}
+
+		return nil, fmt.Errorf("could not locate a CODEOWNERS file")
+	}
+
+	if len(found) > 1 {
+		ignored := make([]string, len(found)-1)
+		for i, file := range found[1:] {
+			ignored[i] = file.Name()
+		}
+
+		cmd.PrintErrf("warning: found multiple CODEOWNERS files, %s only uses %s and ignores %s\n", host, found[0].Name(), strings.Join(ignored, ", "))
+	}
+
+	return found[0], nil
+}
+
+func GetCodeowners(cmd *cobra.Command, opts *RootCmdOptions) (*codeowners.Codeowners, error) {
+	ref, _ := cmd.Flags().GetString("codeowners-ref")
+	return GetCodeownersAt(cmd, opts, ref)
+}
+
+// GetCodeownersAt parses the CODEOWNERS file as it is in the given revision, or in the working tree
+// when ref is empty.
+func GetCodeownersAt(cmd *cobra.Command, opts *RootCmdOptions, ref string) (*codeowners.Codeowners, error) {
+	file, err := ReadCodeownersFileAt(cmd, opts, ref)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsedCodeowners, err := ParseCodeownersFile(cmd, opts, file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Broken lines are skipped, let the user know they aren't being enforced
+	for _, diagnostic := range parsedCodeowners.Diagnostics() {
+		cmd.PrintErrf("warning: %s:%d:%d: %s\n", file.diagnosticLocation(diagnostic), diagnostic.Line, diagnostic.Column, diagnostic.Message)
+	}
+
+	return parsedCodeowners, nil
+}
+
+// ParseCodeownersFile parses the file in its dialect. For Gerrit the root OWNERS file is read along with
+// every other OWNERS file in the repository, unless it was given with --codeowners.
+func ParseCodeownersFile(cmd *cobra.Command, opts *RootCmdOptions, file *CodeownersFile) (*codeowners.Codeowners, error) {
+	if flagPath, _ := cmd.Flags().GetString("codeowners"); file.Dialect != codeowners.DialectGerrit || flagPath != "" {
+		return codeowners.FromReaderDialect(bytes.NewReader(file.Contents), file.Dialect)
+	}
+
+	ownersFiles, err := ReadOwnersFiles(opts, file.Ref, codeowners.OwnersFile{Path: file.Path, Contents: file.Contents})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codeowners.FromOwnersFiles(ownersFiles)
+}
+
+// ReadOwnersFiles reads every OWNERS and .owners.yml file in the repository, as it is in the given revision
+// or in the working tree when ref is empty. The working tree includes files that aren't tracked yet. Files that
+// were already read are used as they are instead of being read again.
+func ReadOwnersFiles(opts *RootCmdOptions, ref string, alreadyRead ...codeowners.OwnersFile) ([]codeowners.OwnersFile, error) {
+	root, err := GetRepoRoot(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var listOutput []byte
+	if ref == "" {
+		listOutput, err = opts.GitExec("-C", root, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	} else {
+		listOutput, err = opts.GitExec("-C", root, "ls-tree", "-r", "-z", "--name-only", ref)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing OWNERS files: %v", err)
+	}
+
+	ownersFiles := []codeowners.OwnersFile{}
+
+	for _, repoPath := range splitNul(listOutput) {
+		if !codeowners.IsOwnersFile(repoPath) || slices.ContainsFunc(ownersFiles, func(file codeowners.OwnersFile) bool { return file.Path == repoPath }) {
+			continue
+		}
+
+		if index := slices.IndexFunc(alreadyRead, func(file codeowners.OwnersFile) bool { return file.Path == repoPath }); index != -1 {
+			ownersFiles = append(ownersFiles, alreadyRead[index])
 			continue
 		}
 
-		defer file.Close()
+		contents, err := readRepoFile(opts, root, ref, repoPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading '%s': %v", repoPath, err)
+		}
+
+		ownersFiles = append(ownersFiles, codeowners.OwnersFile{Path: repoPath, Contents: contents})
+	}
+
+	return ownersFiles, nil
+}
+
+// addOwnerKindFlag adds the --owner-kind flag, for the commands that filter owners with GetOwnerKinds
+func addOwnerKindFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("owner-kind", []string{}, "Only consider owners of the given `kinds`: user, team or email")
+}
+
+// GetOwnerKinds reads the --owner-kind flag, an empty list means every kind of owner
+func GetOwnerKinds(cmd *cobra.Command) ([]codeowners.OwnerKind, error) {
+	kindNames, _ := cmd.Flags().GetStringSlice("owner-kind")
+
+	kinds := []codeowners.OwnerKind{}
+
+	for _, kindName := range kindNames {
+		kind, err := codeowners.ParseOwnerKind(kindName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		kinds = append(kinds, kind)
+	}
 
-		return codeowners.FromReader(file.Reader)
+	return kinds, nil
+}
+
+// splitNul splits the NUL terminated output git produces with -z. Paths in -z output are never
+// quoted, unlike the default output which C-quotes paths with unusual characters.
+func splitNul(output []byte) []string {
+	if len(output) == 0 {
+		return []string{}
 	}
 
-	return nil, fmt.Errorf("could not locate a CODEOWNERS file")
+	return strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+}
+
+// addArgs builds the git arguments that stage exactly the given repository relative paths, without
+// git treating any of them as options or glob patterns.
+func addArgs(root string, paths []string) []string {
+	return append([]string{"-C", root, "--literal-pathspecs", "add", "--"}, paths...)
 }
 
-func GetEdittedFilesScanner(cmd *cobra.Command, opts *RootCmdOptions) (*bufio.Scanner, error) {
-	// TODO: Use flag maybe
-	diffOutput, err := opts.GitExec("--no-pager", "diff", "--name-only")
+// GetTrackedFiles lists every file tracked in the repository
+func GetTrackedFiles(cmd *cobra.Command, opts *RootCmdOptions) ([]string, error) {
+	root, err := GetRepoRoot(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Run from the root, otherwise only files under the current directory are listed
+	lsFilesOutput, err := opts.GitExec("-C", root, "ls-files", "-z")
 
 	if err != nil {
-		return nil, fmt.Errorf("error finding files in the working tree")
+		return nil, fmt.Errorf("error listing tracked files: %v", err)
 	}
 
-	return bufio.NewScanner(bytes.NewReader(diffOutput)), nil
+	return splitNul(lsFilesOutput), nil
 }
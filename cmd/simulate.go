@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+func newCmdSimulate(opts *RootCmdOptions) *cobra.Command {
+	var addRules []string
+	var removeLines []int
+	var line int
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Preview how adding or removing CODEOWNERS rules changes ownership",
+		Long: `Add or remove rules from the CODEOWNERS file in memory, then evaluate every tracked file against it and
+report the files that would gain, lose or switch owners. The CODEOWNERS file itself is not changed.
+
+Added rules go to the end of the file, where they win over every other rule, unless --line is given. Line numbers
+given to --remove and --line refer to the file as it is now.`,
+		Example: `  $ gh codeowners simulate --add "/services/billing/ @org/billing"
+  $ gh codeowners simulate --remove 12 --remove 14
+  $ gh codeowners simulate --add "/docs/ @org/docs" --line 3`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(addRules) == 0 && len(removeLines) == 0 {
+				return fmt.Errorf("nothing to simulate, use --add or --remove")
+			}
+
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			proposed := codeowners.Clone()
+
+			// Remove from the bottom up so the lines that haven't been removed yet don't move
+			sortedLines := slices.Clone(removeLines)
+			slices.Sort(sortedLines)
+			sortedLines = slices.Compact(sortedLines)
+			slices.Reverse(sortedLines)
+
+			for _, removeLine := range sortedLines {
+				removed, err := proposed.RemoveRule(removeLine)
+
+				if err != nil {
+					return err
+				}
+
+				cmd.Printf("Removing line %d: %s\n", removeLine, removed.String())
+			}
+
+			insertLine := line
+			if insertLine > 0 {
+				// Account for the removed lines above where the rules are going
+				for _, removeLine := range sortedLines {
+					if removeLine < line {
+						insertLine--
+					}
+				}
+			}
+
+			for _, rule := range addRules {
+				added, err := proposed.InsertRule(insertLine, rule)
+
+				if err != nil {
+					return fmt.Errorf("error adding rule '%s': %v", rule, err)
+				}
+
+				cmd.Printf("Adding line %d: %s\n", added.Line, added.String())
+
+				if insertLine > 0 {
+					insertLine = added.Line + 1
+				}
+			}
+
+			cmd.Println()
+
+			changes := diffOwnership(codeowners, proposed, trackedFiles)
+
+			if len(changes) == 0 {
+				cmd.Println("No files would change owners")
+				return nil
+			}
+
+			printOwnershipChanges(cmd, changes)
+
+			cmd.Printf("%d of %d files would change owners\n", len(changes), len(trackedFiles))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&addRules, "add", []string{}, "Add the given `rule`, can be repeated")
+	cmd.Flags().IntSliceVar(&removeLines, "remove", []int{}, "Remove the rule on the given `line`, can be repeated")
+	cmd.Flags().IntVar(&line, "line", 0, "Insert added rules before the given `line` instead of at the end of the file")
+
+	return cmd
+}
@@ -2,47 +2,64 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/justindbaur/gh-codeowners/codeowners"
 	"github.com/spf13/cobra"
 )
 
 func newCmdStage(opts *RootCmdOptions) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use: "stage team",
 		Args: func(cmd *cobra.Command, args []string) error {
 			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
 				return fmt.Errorf("required team argument missing")
 			}
 
-			// TODO: Validate that it's a valid team
-			return nil
+			_, err := codeowners.ParseOwner(args[0])
+			return err
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			edittedFilesScanner, err := GetEdittedFilesScanner(cmd, opts)
+			kinds, err := GetOwnerKinds(cmd)
+
+			if err != nil {
+				return err
+			}
+
+			changes, err := GetChanges(cmd, opts)
 
 			if err != nil {
-				return fmt.Errorf("error getting editted files scanner: %v", err)
+				return fmt.Errorf("error getting changed files: %v", err)
 			}
 
-			codeowners, err := GetCodeowners(cmd, opts)
+			co, err := GetCodeowners(cmd, opts)
 
 			if err != nil {
 				return fmt.Errorf("error getting codeowners info: %v", err)
 			}
 
-			team := args[0]
+			root, err := GetRepoRoot(opts)
+
+			if err != nil {
+				return err
+			}
+
+			// Already validated by Args
+			team, _ := codeowners.ParseOwner(args[0])
 
 			foundFileToStage := false
 
 			// Do file staging
-			for edittedFilesScanner.Scan() {
-				if codeowners.IsOwnedBy(edittedFilesScanner.Bytes(), team) {
+			for _, change := range changes {
+				// Renames are staged if the team owns either side of the move, owners are compared
+				// ignoring case like GitHub does
+				if slices.ContainsFunc(changeOwners(co, change, kinds), team.Equal) {
 					foundFileToStage = true
-					_, err := opts.GitExec("add", edittedFilesScanner.Text())
+					_, err := opts.GitExec(addArgs(root, change.Paths())...)
 					if err != nil {
-						return fmt.Errorf("failed to stage '%s': %v", edittedFilesScanner.Text(), err)
+						return fmt.Errorf("failed to stage '%s': %v", change, err)
 					}
-					cmd.Printf("Staged: %s\n", edittedFilesScanner.Text())
+					cmd.Printf("Staged: %s\n", change)
 				}
 			}
 
@@ -53,4 +70,9 @@ func newCmdStage(opts *RootCmdOptions) *cobra.Command {
 			return nil
 		},
 	}
+
+	addChangeFlags(cmd)
+	addOwnerKindFlag(cmd)
+
+	return cmd
 }
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+// How many of the other people changing a stale rule's files are shown
+const staleTopAuthors = 3
+
+// ruleActivity is who changed the files a rule owns
+type ruleActivity struct {
+	Entry *codeowners.OwnerEntry
+	// Commits by the owners listed on the rule
+	OwnerCommits int
+	// Commits by anyone else, by the owner they count as
+	OtherCommits map[string]int
+}
+
+// ownerCommitCount is how many commits someone counted as the owner made
+type ownerCommitCount struct {
+	Owner   string
+	Commits int
+}
+
+// IsStale reports whether the owners of the rule didn't change its files but somebody else did
+func (activity *ruleActivity) IsStale() bool {
+	return len(activity.Entry.ParsedOwners()) > 0 && activity.OwnerCommits == 0 && len(activity.OtherCommits) > 0
+}
+
+// SortedOtherCommits orders the other people changing the rule's files by their commits, most first,
+// breaking ties by name
+func (activity *ruleActivity) SortedOtherCommits() []ownerCommitCount {
+	sorted := make([]ownerCommitCount, 0, len(activity.OtherCommits))
+
+	for owner, commits := range activity.OtherCommits {
+		sorted = append(sorted, ownerCommitCount{Owner: owner, Commits: commits})
+	}
+
+	slices.SortFunc(sorted, func(a, b ownerCommitCount) int {
+		return cmp.Or(b.Commits-a.Commits, strings.Compare(a.Owner, b.Owner))
+	})
+
+	return sorted
+}
+
+func newCmdStale(opts *RootCmdOptions) *cobra.Command {
+	var since string
+	var teamsPath string
+
+	cmd := &cobra.Command{
+		Use:   "stale",
+		Short: "Find CODEOWNERS rules whose owners no longer change the files they own",
+		Long: `Compare the owners of every CODEOWNERS rule with who changed the files the rule owns in the git log since
+--since, six months by default. A rule is stale when none of its owners changed its files but others did, which
+usually means the code moved to another team.
+
+Authors count as the teams they are on in .github/codeowners-teams.yml, or the file given with --teams, and as
+themselves by their GitHub login when they commit with a noreply address, otherwise by their email:
+
+  "@org/payments":
+    - alice@example.com
+    - Bob Smith`,
+		Example: "  $ gh codeowners stale\n  $ gh codeowners stale --since \"1 year ago\"",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			root, err := GetRepoRoot(opts)
+
+			if err != nil {
+				return err
+			}
+
+			teams, err := readAuthorTeams(opts, root, teamsPath)
+
+			if err != nil {
+				return err
+			}
+
+			history, err := readHistory(opts, root, since)
+
+			if err != nil {
+				return err
+			}
+
+			activities := ruleActivities(codeowners.Usage(trackedFiles), history, teams)
+
+			staleRules := 0
+			for _, activity := range activities {
+				if !activity.IsStale() {
+					continue
+				}
+
+				if staleRules == 0 {
+					cmd.Printf("Stale rules, their owners haven't changed their files since %s but others have:\n", since)
+				}
+				staleRules++
+
+				others := []string{}
+				for i, other := range activity.SortedOtherCommits() {
+					if i == staleTopAuthors {
+						others = append(others, fmt.Sprintf("and %d more", len(activity.OtherCommits)-staleTopAuthors))
+						break
+					}
+
+					label := "commit"
+					if other.Commits > 1 {
+						label = "commits"
+					}
+
+					others = append(others, fmt.Sprintf("%s (%d %s)", other.Owner, other.Commits, label))
+				}
+
+				cmd.Printf("  line %d: %s\n", activity.Entry.Line, activity.Entry.String())
+				cmd.Printf("    changed by %s\n", strings.Join(others, ", "))
+			}
+
+			cmd.Printf("%d stale rules out of %d\n", staleRules, len(activities))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "6 months ago", "Only look at changes since the given `date`, anything git log --since accepts")
+	cmd.Flags().StringVar(&teamsPath, "teams", "", "Read the teams of commit authors from the given `file` instead of "+defaultTeamsPath)
+
+	return cmd
+}
+
+// ruleActivities counts the commits that changed the files each rule owns, in file order. A commit counts
+// once per rule however many of its files it changed.
+func ruleActivities(usages []codeowners.RuleUsage, history []historyCommit, teams *authorTeams) []ruleActivity {
+	activities := make([]ruleActivity, len(usages))
+	rulesByFile := map[string][]int{}
+
+	for i := range usages {
+		activities[i] = ruleActivity{Entry: usages[i].Entry, OtherCommits: map[string]int{}}
+
+		for _, file := range usages[i].WonFiles {
+			rulesByFile[file] = append(rulesByFile[file], i)
+		}
+	}
+
+	for _, commit := range history {
+		changedRules := []int{}
+
+		for _, changed := range commit.Files {
+			for _, rule := range rulesByFile[changed.Path] {
+				if !slices.Contains(changedRules, rule) {
+					changedRules = append(changedRules, rule)
+				}
+			}
+		}
+
+		if len(changedRules) == 0 {
+			continue
+		}
+
+		// Everyone the author counts as, to see if they're one of the listed owners
+		identities := []codeowners.Owner{}
+		for _, team := range teams.teamsOf(commit) {
+			if owner, err := codeowners.ParseOwner(team); err == nil {
+				identities = append(identities, owner)
+			}
+		}
+
+		if identity, ok := authorIdentity(commit); ok {
+			if owner, err := codeowners.ParseOwner(identity); err == nil {
+				identities = append(identities, owner)
+			}
+		}
+
+		for _, rule := range changedRules {
+			activity := &activities[rule]
+
+			isOwner := slices.ContainsFunc(activity.Entry.ParsedOwners(), func(owner codeowners.Owner) bool {
+				return slices.ContainsFunc(identities, owner.Equal)
+			})
+
+			if isOwner {
+				activity.OwnerCommits++
+				continue
+			}
+
+			for _, owner := range teams.ownersOf(commit) {
+				activity.OtherCommits[owner]++
+			}
+		}
+	}
+
+	return activities
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleActivities(t *testing.T) {
+	co, err := codeowners.FromReader(bytes.NewBufferString(`* @org/everyone
+/billing/ @org/payments
+/docs/ @octocat
+/legacy/
+`))
+	assert.NoError(t, err)
+
+	teams := &authorTeams{teams: map[string][]string{
+		"alice@example.com": {"@org/payments"},
+		"bob@example.com":   {"@org/billing"},
+	}}
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	history := []historyCommit{
+		{Time: now, Email: "bob@example.com", Files: []historyFile{{Path: "billing/a.go", Lines: 1}, {Path: "billing/b.go", Lines: 1}}},
+		{Time: now, Email: "carol@example.com", Files: []historyFile{{Path: "billing/a.go", Lines: 1}, {Path: "docs/index.md", Lines: 1}}},
+		{Time: now, Email: "12345+octocat@users.noreply.github.com", Files: []historyFile{{Path: "docs/index.md", Lines: 1}}},
+		{Time: now, Email: "carol@example.com", Files: []historyFile{{Path: "legacy/old.go", Lines: 1}, {Path: "deleted.go", Lines: 1}}},
+	}
+
+	usages := co.Usage([]string{"billing/a.go", "billing/b.go", "docs/index.md", "legacy/old.go", "README.md"})
+	activities := ruleActivities(usages, history, teams)
+
+	type summary struct {
+		line   int
+		owner  int
+		others map[string]int
+		stale  bool
+	}
+
+	actual := []summary{}
+	for _, activity := range activities {
+		actual = append(actual, summary{activity.Entry.Line, activity.OwnerCommits, activity.OtherCommits, activity.IsStale()})
+	}
+
+	assert.Equal(t, []summary{
+		{line: 1, owner: 0, others: map[string]int{}},
+		{line: 2, owner: 0, others: map[string]int{"@org/billing": 1, "carol@example.com": 1}, stale: true},
+		{line: 3, owner: 1, others: map[string]int{"carol@example.com": 1}},
+		// Intentionally unowned
+		{line: 4, owner: 0, others: map[string]int{"carol@example.com": 1}},
+	}, actual)
+}
+
+func TestRuleActivity_SortedOtherCommits(t *testing.T) {
+	activity := ruleActivity{OtherCommits: map[string]int{"@org/web": 2, "@bob": 5, "@alice": 2}}
+
+	assert.Equal(t, []ownerCommitCount{
+		{Owner: "@bob", Commits: 5},
+		{Owner: "@alice", Commits: 2},
+		{Owner: "@org/web", Commits: 2},
+	}, activity.SortedOtherCommits())
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+// How quickly older changes count for less when suggesting owners, a change this old counts half as much as
+// the newest change
+const suggestHalfLifeDays = 90
+
+// ownerSuggestion is a rule suggested for unowned files
+type ownerSuggestion struct {
+	Pattern string
+	Owner   string
+	// Unowned files the rule would give an owner
+	Files int
+}
+
+func newCmdSuggest(opts *RootCmdOptions) *cobra.Command {
+	var since string
+	var teamsPath string
+	var write bool
+
+	cmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest owners for unowned files from the git history",
+		Long: `Look at who changed each unowned file in the git log and suggest CODEOWNERS rules for them. Bigger and
+more recent changes count for more. Each file is suggested for whoever changed it the most, and files are
+grouped into the biggest directory where every file is unowned and has the same suggested owner.
+
+Authors are mapped to teams with .github/codeowners-teams.yml, or the file given with --teams, which lists
+the members of each team by commit email or name:
+
+  "@org/payments":
+    - alice@example.com
+    - Bob Smith
+
+Authors that aren't on a team are suggested by their GitHub login when they commit with a noreply address,
+otherwise by their email.
+
+The suggested rules are printed ready to paste into CODEOWNERS, or added to it with --write.`,
+		Example: "  $ gh codeowners suggest\n  $ gh codeowners suggest --since \"6 months ago\" --write",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			file, err := ReadCodeownersFile(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			current, err := ParseCodeownersFile(cmd, opts, file)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			// Files left without owners by a rule on purpose don't need any
+			unownedFiles := map[string]bool{}
+			for _, trackedFile := range trackedFiles {
+				if len(current.FindOwners([]byte(trackedFile))) == 0 && !current.IsIntentionallyUnowned([]byte(trackedFile)) {
+					unownedFiles[trackedFile] = true
+				}
+			}
+
+			if len(unownedFiles) == 0 {
+				cmd.Println("Every file has an owner")
+				return nil
+			}
+
+			root, err := GetRepoRoot(opts)
+
+			if err != nil {
+				return err
+			}
+
+			teams, err := readAuthorTeams(opts, root, teamsPath)
+
+			if err != nil {
+				return err
+			}
+
+			history, err := readHistory(opts, root, since)
+
+			if err != nil {
+				return err
+			}
+
+			suggestedOwners := suggestFileOwners(history, teams, unownedFiles)
+			suggestions := suggestRules(trackedFiles, unownedFiles, suggestedOwners)
+
+			if len(suggestions) == 0 {
+				cmd.Printf("None of the %d unowned files changed since %s\n", len(unownedFiles), since)
+				return nil
+			}
+
+			if write {
+				return editCodeownersFile(cmd, opts, &editOptions{yes: true}, file, func(document *codeowners.Document) ([]codeowners.DocumentChange, error) {
+					changes := []codeowners.DocumentChange{}
+
+					for _, suggestion := range suggestions {
+						added, err := document.AddRule(suggestion.Pattern, []string{suggestion.Owner})
+
+						if err != nil {
+							return nil, fmt.Errorf("error adding rule for '%s': %v", suggestion.Pattern, err)
+						}
+
+						changes = append(changes, added...)
+					}
+
+					return changes, nil
+				})
+			}
+
+			width := 0
+			for _, suggestion := range suggestions {
+				width = max(width, len(suggestion.Pattern)+1)
+			}
+
+			covered := 0
+			for _, suggestion := range suggestions {
+				cmd.Printf("%-*s%s\n", width, suggestion.Pattern, suggestion.Owner)
+				covered += suggestion.Files
+			}
+
+			// On stderr so the rules on stdout can be piped straight into a file
+			cmd.PrintErrf("Suggested owners for %d of %d unowned files\n", covered, len(unownedFiles))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "1 year ago", "Only look at changes since the given `date`, anything git log --since accepts")
+	cmd.Flags().StringVar(&teamsPath, "teams", "", "Read the teams of commit authors from the given `file` instead of "+defaultTeamsPath)
+	cmd.Flags().BoolVar(&write, "write", false, "Add the suggested rules to the CODEOWNERS file")
+
+	return cmd
+}
+
+// suggestFileOwners picks the owner that changed each of the files the most. Every change counts for the lines
+// it changed, halved for every suggestHalfLifeDays it is older than the newest change.
+func suggestFileOwners(history []historyCommit, teams *authorTeams, files map[string]bool) map[string]string {
+	if len(history) == 0 {
+		return map[string]string{}
+	}
+
+	newest := history[0].Time
+	for _, commit := range history {
+		if commit.Time.After(newest) {
+			newest = commit.Time
+		}
+	}
+
+	scores := map[string]map[string]float64{}
+
+	for _, commit := range history {
+		ageDays := newest.Sub(commit.Time).Hours() / 24
+		recency := math.Pow(0.5, ageDays/suggestHalfLifeDays)
+		owners := teams.ownersOf(commit)
+
+		for _, changed := range commit.Files {
+			if !files[changed.Path] {
+				continue
+			}
+
+			if scores[changed.Path] == nil {
+				scores[changed.Path] = map[string]float64{}
+			}
+
+			for _, owner := range owners {
+				scores[changed.Path][owner] += float64(changed.Lines) * recency
+			}
+		}
+	}
+
+	fileOwners := map[string]string{}
+
+	for file, ownerScores := range scores {
+		best := ""
+
+		for owner, score := range ownerScores {
+			// Ties go to the first owner by name, so the suggestion is the same every time
+			if best == "" || score > ownerScores[best] || score == ownerScores[best] && owner < best {
+				best = owner
+			}
+		}
+
+		if best != "" {
+			fileOwners[file] = best
+		}
+	}
+
+	return fileOwners
+}
+
+// suggestRules groups the suggested owners of files into rules. A directory gets a single rule when every file
+// in it is unowned and the files that have a suggested owner all have the same one, otherwise its
+// subdirectories and files are looked at on their own.
+func suggestRules(trackedFiles []string, unownedFiles map[string]bool, fileOwners map[string]string) []ownerSuggestion {
+	sortedFiles := slices.Clone(trackedFiles)
+	slices.Sort(sortedFiles)
+
+	return suggestDirectoryRules("", sortedFiles, unownedFiles, fileOwners)
+}
+
+// suggestDirectoryRules suggests rules for the sorted files in a directory, an empty dir being the root
+func suggestDirectoryRules(dir string, files []string, unownedFiles map[string]bool, fileOwners map[string]string) []ownerSuggestion {
+	owners := []string{}
+	allUnowned := true
+
+	for _, file := range files {
+		allUnowned = allUnowned && unownedFiles[file]
+
+		if owner, ok := fileOwners[file]; ok {
+			if !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	if len(owners) == 0 {
+		return []ownerSuggestion{}
+	}
+
+	if allUnowned && len(owners) == 1 {
+		pattern := "*"
+		if dir != "" {
+			pattern = "/" + codeowners.EscapePattern(dir) + "/"
+		}
+
+		return []ownerSuggestion{{Pattern: pattern, Owner: owners[0], Files: len(files)}}
+	}
+
+	suggestions := []ownerSuggestion{}
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	for start := 0; start < len(files); {
+		name := strings.TrimPrefix(files[start], prefix)
+		subdir, _, isNested := strings.Cut(name, "/")
+
+		if !isNested {
+			if owner, ok := fileOwners[files[start]]; ok {
+				suggestions = append(suggestions, ownerSuggestion{Pattern: "/" + codeowners.EscapePattern(files[start]), Owner: owner, Files: 1})
+			}
+
+			start++
+			continue
+		}
+
+		// Sorted files in the same subdirectory are next to each other
+		subdirPrefix := prefix + subdir + "/"
+		end := start
+		for end < len(files) && strings.HasPrefix(files[end], subdirPrefix) {
+			end++
+		}
+
+		suggestions = append(suggestions, suggestDirectoryRules(path.Join(dir, subdir), files[start:end], unownedFiles, fileOwners)...)
+		start = end
+	}
+
+	return suggestions
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		tracked    []string
+		unowned    []string
+		fileOwners map[string]string
+		expected   []ownerSuggestion
+	}{
+		{
+			name:       "Nothing owned",
+			tracked:    []string{"src/main.go", "README.md"},
+			unowned:    []string{"src/main.go", "README.md"},
+			fileOwners: map[string]string{"src/main.go": "@org/core"},
+			expected:   []ownerSuggestion{{Pattern: "*", Owner: "@org/core", Files: 2}},
+		},
+		{
+			name:    "Coarsest consistent directory",
+			tracked: []string{"src/main.go", "tools/a/one.sh", "tools/a/two.sh", "tools/b/three.sh", "tools/run.sh"},
+			unowned: []string{"tools/a/one.sh", "tools/a/two.sh", "tools/b/three.sh", "tools/run.sh"},
+			fileOwners: map[string]string{
+				"tools/a/one.sh":   "@org/build",
+				"tools/b/three.sh": "@org/build",
+			},
+			expected: []ownerSuggestion{{Pattern: "/tools/", Owner: "@org/build", Files: 4}},
+		},
+		{
+			name:    "Split where owners differ",
+			tracked: []string{"tools/a/one.sh", "tools/a/two.sh", "tools/b/three.sh", "tools/run.sh", "tools-old/x.sh"},
+			unowned: []string{"tools/a/one.sh", "tools/a/two.sh", "tools/b/three.sh", "tools/run.sh", "tools-old/x.sh"},
+			fileOwners: map[string]string{
+				"tools/a/one.sh":   "@org/build",
+				"tools/b/three.sh": "@org/release",
+				"tools/run.sh":     "@octocat",
+			},
+			expected: []ownerSuggestion{
+				{Pattern: "/tools/a/", Owner: "@org/build", Files: 2},
+				{Pattern: "/tools/b/", Owner: "@org/release", Files: 1},
+				{Pattern: "/tools/run.sh", Owner: "@octocat", Files: 1},
+			},
+		},
+		{
+			name:       "Owned files aren't taken over",
+			tracked:    []string{"docs/index.md", "docs/api/v1.md", "docs/guide.md"},
+			unowned:    []string{"docs/api/v1.md", "docs/guide.md"},
+			fileOwners: map[string]string{"docs/api/v1.md": "@org/api", "docs/guide.md": "@org/api"},
+			expected: []ownerSuggestion{
+				{Pattern: "/docs/api/", Owner: "@org/api", Files: 1},
+				{Pattern: "/docs/guide.md", Owner: "@org/api", Files: 1},
+			},
+		},
+		{
+			name:       "Escaped paths",
+			tracked:    []string{"my docs/#1.md", "src/main.go"},
+			unowned:    []string{"my docs/#1.md"},
+			fileOwners: map[string]string{"my docs/#1.md": "@org/docs"},
+			expected:   []ownerSuggestion{{Pattern: `/my\ docs/`, Owner: "@org/docs", Files: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unownedFiles := map[string]bool{}
+			for _, file := range test.unowned {
+				unownedFiles[file] = true
+			}
+
+			assert.Equal(t, test.expected, suggestRules(test.tracked, unownedFiles, test.fileOwners))
+		})
+	}
+}
+
+func TestSuggestFileOwners(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	teams := &authorTeams{teams: map[string][]string{
+		"alice@example.com": {"@org/payments"},
+		"bob smith":         {"@org/payments"},
+	}}
+
+	history := []historyCommit{
+		// Newest, small change
+		{Time: now, Email: "carol@example.com", Name: "Carol", Files: []historyFile{{Path: "billing/invoice.go", Lines: 30}}},
+		// A year old, so counts for a sixteenth
+		{Time: now.AddDate(-1, 0, 0), Email: "alice@example.com", Name: "Alice", Files: []historyFile{{Path: "billing/invoice.go", Lines: 400}}},
+		{Time: now, Email: "bob@example.com", Name: "Bob Smith", Files: []historyFile{{Path: "billing/tax.go", Lines: 5}, {Path: "owned.go", Lines: 100}}},
+		{Time: now, Email: "12345+octocat@users.noreply.github.com", Name: "Mona", Files: []historyFile{{Path: "billing/tax.go", Lines: 5}}},
+	}
+
+	fileOwners := suggestFileOwners(history, teams, map[string]bool{"billing/invoice.go": true, "billing/tax.go": true})
+
+	assert.Equal(t, map[string]string{
+		"billing/invoice.go": "carol@example.com",
+		"billing/tax.go":     "@octocat",
+	}, fileOwners)
+}
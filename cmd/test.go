@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the ownership assertions are read from, relative to the root of the repository
+const defaultTestsPath = ".github/codeowners-tests.yml"
+
+// ownershipAssertion is the owners a file is expected to have
+type ownershipAssertion struct {
+	Path   string
+	Owners []string
+	// Line of the assertion in the tests file
+	Line int
+}
+
+func newCmdTest(opts *RootCmdOptions) *cobra.Command {
+	var testsPath string
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Check files are owned by who they are expected to be",
+		Long: `Read the expected owners of files from .github/codeowners-tests.yml, or the file given with --file, and
+check them against the CODEOWNERS file. Each failure shows the missing and unexpected owners and the rule
+that matched the file. Exits with a non-zero code when any assertion fails, so it can be run in CI.
+
+The tests file maps paths to their owners, as a list or separated by spaces. Owners are compared ignoring
+case and order, and an empty list means the file is expected to have no owners:
+
+  src/main.go: ["@org/core"]
+  docs/index.md: "@org/docs @octocat"
+  LICENSE: []`,
+		Example: "  $ gh codeowners test\n  $ gh codeowners test --codeowners-ref origin/main",
+		Args:    cobra.NoArgs,
+		// A failed assertion isn't a usage problem
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var contents []byte
+			var err error
+
+			if testsPath != "" {
+				contents, err = readAllFile(opts, testsPath)
+			} else {
+				testsPath = defaultTestsPath
+
+				var root string
+				root, err = GetRepoRoot(opts)
+
+				if err == nil {
+					contents, err = readRepoFile(opts, root, "", defaultTestsPath)
+				}
+			}
+
+			if err != nil {
+				return fmt.Errorf("error reading '%s': %v", filepath.ToSlash(testsPath), err)
+			}
+
+			assertions, err := parseOwnershipAssertions(contents)
+
+			if err != nil {
+				return fmt.Errorf("error parsing '%s': %v", filepath.ToSlash(testsPath), err)
+			}
+
+			if len(assertions) == 0 {
+				return fmt.Errorf("'%s' doesn't have any assertions", filepath.ToSlash(testsPath))
+			}
+
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			failures := 0
+
+			for _, assertion := range assertions {
+				actual := codeowners.FindOwners([]byte(assertion.Path))
+				missing := ownersNotIn(assertion.Owners, actual)
+				unexpected := ownersNotIn(actual, assertion.Owners)
+
+				if len(missing) == 0 && len(unexpected) == 0 {
+					continue
+				}
+
+				failures++
+
+				cmd.Printf("FAIL %s (%s:%d)\n", assertion.Path, filepath.ToSlash(testsPath), assertion.Line)
+
+				for _, owner := range missing {
+					cmd.Printf("  - %s\n", owner)
+				}
+
+				for _, owner := range unexpected {
+					cmd.Printf("  + %s\n", owner)
+				}
+
+				matches := codeowners.MatchSections([]byte(assertion.Path))
+
+				if len(matches) == 0 {
+					cmd.Println("  Rule: no rule matches the file")
+				}
+
+				for _, match := range matches {
+					cmd.Printf("  Rule: line %d: %s\n", match.Entry.Line, match.Entry.String())
+				}
+			}
+
+			if failures > 0 {
+				return fmt.Errorf("%d of %d assertions failed", failures, len(assertions))
+			}
+
+			cmd.Printf("%d assertions passed\n", len(assertions))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&testsPath, "file", "", "Read the assertions from the given `file` instead of "+defaultTestsPath)
+
+	return cmd
+}
+
+// parseOwnershipAssertions reads a mapping of paths to their expected owners, given as a list or a single string
+// of owners separated by spaces
+func parseOwnershipAssertions(contents []byte) ([]ownershipAssertion, error) {
+	var document yaml.Node
+
+	if err := yaml.NewDecoder(bytes.NewReader(contents)).Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return []ownershipAssertion{}, nil
+		}
+
+		return nil, err
+	}
+
+	root := document.Content[0]
+
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of paths to their owners", root.Line)
+	}
+
+	assertions := []ownershipAssertion{}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		assertion := ownershipAssertion{Path: strings.TrimPrefix(key.Value, "/"), Owners: []string{}, Line: key.Line}
+
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			// Nothing after the path expects no owners, like an empty list
+		case value.Kind == yaml.ScalarNode:
+			assertion.Owners = strings.Fields(value.Value)
+		case value.Kind == yaml.SequenceNode && !slices.ContainsFunc(value.Content, func(owner *yaml.Node) bool { return owner.Kind != yaml.ScalarNode }):
+			for _, owner := range value.Content {
+				assertion.Owners = append(assertion.Owners, owner.Value)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: expected the owners of '%s' as a list or separated by spaces", value.Line, key.Value)
+		}
+
+		assertions = append(assertions, assertion)
+	}
+
+	return assertions, nil
+}
+
+// ownersNotIn returns the owners that aren't in the other list, comparing valid owners ignoring case
+func ownersNotIn(owners []string, other []string) []string {
+	notIn := []string{}
+
+	for _, owner := range owners {
+		parsedOwner, err := codeowners.ParseOwner(owner)
+
+		found := slices.ContainsFunc(other, func(otherOwner string) bool {
+			parsedOther, otherErr := codeowners.ParseOwner(otherOwner)
+
+			if err != nil || otherErr != nil {
+				return owner == otherOwner
+			}
+
+			return parsedOwner.Equal(parsedOther)
+		})
+
+		if !found {
+			notIn = append(notIn, owner)
+		}
+	}
+
+	return notIn
+}
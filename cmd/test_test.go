@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOwnershipAssertions(t *testing.T) {
+	assertions, err := parseOwnershipAssertions([]byte(`# Expected owners
+src/main.go: ["@org/core", "@octocat"]
+/docs/index.md: "@org/docs  docs@example.com"
+LICENSE: []
+NOTICE:
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ownershipAssertion{
+		{Path: "src/main.go", Owners: []string{"@org/core", "@octocat"}, Line: 2},
+		{Path: "docs/index.md", Owners: []string{"@org/docs", "docs@example.com"}, Line: 3},
+		{Path: "LICENSE", Owners: []string{}, Line: 4},
+		{Path: "NOTICE", Owners: []string{}, Line: 5},
+	}, assertions)
+
+	assertions, err = parseOwnershipAssertions([]byte(""))
+
+	assert.NoError(t, err)
+	assert.Empty(t, assertions)
+
+	_, err = parseOwnershipAssertions([]byte("src/main.go:\n  owners: \"@org/core\"\n"))
+
+	assert.EqualError(t, err, "line 2: expected the owners of 'src/main.go' as a list or separated by spaces")
+
+	_, err = parseOwnershipAssertions([]byte("- src/main.go\n"))
+
+	assert.EqualError(t, err, "line 1: expected a mapping of paths to their owners")
+}
+
+func TestOwnersNotIn(t *testing.T) {
+	assert.Equal(t, []string{"@org/docs"}, ownersNotIn([]string{"@Org/Core", "@org/docs"}, []string{"@org/core", "@octocat"}))
+	assert.Equal(t, []string{}, ownersNotIn([]string{"not-an-owner"}, []string{"not-an-owner"}))
+}
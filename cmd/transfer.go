@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/justindbaur/gh-codeowners/codeowners"
+	"github.com/spf13/cobra"
+)
+
+func newCmdTransfer(opts *RootCmdOptions) *cobra.Command {
+	editOpts := &editOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "transfer <from-owner> <to-owner>",
+		Short: "Move everything an owner owns to another owner",
+		Long: `Replace an owner with another everywhere it is listed in the CODEOWNERS file, including GitLab section
+default owners and Bitbucket group members. Owners are compared ignoring case. Rules that already list the new
+owner just lose the old one.
+
+The changed lines and the files that would change owners are shown before anything is written.`,
+		Example: `  $ gh codeowners transfer @org/payments @org/billing
+  $ gh codeowners transfer @octocat @hubot --dry-run`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := codeowners.ParseOwner(args[0])
+
+			if err != nil {
+				return err
+			}
+
+			to, err := codeowners.ParseOwner(args[1])
+
+			if err != nil {
+				return err
+			}
+
+			if from.Equal(to) {
+				return fmt.Errorf("'%s' and '%s' are the same owner", args[0], args[1])
+			}
+
+			return editCodeowners(cmd, opts, editOpts, func(document *codeowners.Document) ([]codeowners.DocumentChange, error) {
+				changes := document.ReplaceOwner(from, to)
+
+				if len(changes) == 0 {
+					return nil, fmt.Errorf("'%s' doesn't own anything", args[0])
+				}
+
+				return changes, nil
+			})
+		},
+	}
+
+	editOpts.addFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newCmdUnused(opts *RootCmdOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unused",
+		Short: "Find CODEOWNERS rules that have no effect",
+		Long: `Evaluate every CODEOWNERS rule against all tracked files and report dead rules, which don't match any
+tracked file, and shadowed rules, which match files but are always overridden by a later rule.`,
+		Example: "  $ gh codeowners unused",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trackedFiles, err := GetTrackedFiles(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting tracked files: %v", err)
+			}
+
+			codeowners, err := GetCodeowners(cmd, opts)
+
+			if err != nil {
+				return fmt.Errorf("error getting codeowners info: %v", err)
+			}
+
+			usages := codeowners.Usage(trackedFiles)
+
+			deadRules := 0
+			for _, usage := range usages {
+				if usage.IsDead() {
+					if deadRules == 0 {
+						cmd.Println("Dead rules, these match no tracked files:")
+					}
+					deadRules++
+					cmd.Printf("  line %d: %s\n", usage.Entry.Line, usage.Entry.String())
+				}
+			}
+
+			shadowedRules := 0
+			for _, usage := range usages {
+				if usage.IsShadowed() {
+					if shadowedRules == 0 {
+						cmd.Println("Shadowed rules, these match files but a later rule always wins:")
+					}
+					shadowedRules++
+
+					shadowedBy := make([]string, len(usage.ShadowedBy))
+					for i, line := range usage.ShadowedBy {
+						shadowedBy[i] = fmt.Sprint(line)
+					}
+
+					label := "line"
+					if len(shadowedBy) > 1 {
+						label = "lines"
+					}
+
+					cmd.Printf("  line %d: %s (overridden by %s %s)\n", usage.Entry.Line, usage.Entry.String(), label, strings.Join(shadowedBy, ", "))
+				}
+			}
+
+			cmd.Printf("%d dead and %d shadowed rules out of %d\n", deadRules, shadowedRules, len(usages))
+			return nil
+		},
+	}
+}
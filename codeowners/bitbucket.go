@@ -0,0 +1,246 @@
+package codeowners
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bitbucketGroup is a @@@Group definition, rules refer to it as @@Group
+type bitbucketGroup struct {
+	name          string
+	line          int
+	members       []string
+	memberColumns []int
+}
+
+// bitbucketGroupRef is a reference to a group from a merge check
+type bitbucketGroupRef struct {
+	name   string
+	line   int
+	column int
+}
+
+// bitbucketRules collects the lines of a Bitbucket CODEOWNERS file that aren't rules
+type bitbucketRules struct {
+	// Keyed by the lower case name, group names are case insensitive
+	groups    map[string]*bitbucketGroup
+	checkRefs []bitbucketGroupRef
+}
+
+// Check(@@Group >= 2), a merge check requiring approvals from members of a group
+var (
+	bitbucketCheckRE     = regexp.MustCompile(`\ACheck\((.*)\)\z`)
+	bitbucketCheckTermRE = regexp.MustCompile(`@@([^\s()&|<>=]+)\s*>=\s*(\d+)`)
+)
+
+// parseDirective parses a group definition, merge check or CODEOWNERS.* setting. Returns false if
+// the line is something else, which leaves it to be parsed as a rule.
+func (rules *bitbucketRules) parseDirective(line string, lineNumber int) (bool, *Diagnostic) {
+	trimmed := strings.TrimLeft(line, " \t")
+	column := len(line) - len(trimmed) + 1
+
+	invalid := func(format string, args ...any) (bool, *Diagnostic) {
+		return true, &Diagnostic{
+			Line:     lineNumber,
+			Column:   column,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "@@@"):
+		tokens, _ := tokenizeLine(line)
+		name := strings.TrimPrefix(tokens[0].text, "@@@")
+
+		if name == "" {
+			return invalid("group definition '%s' has no name", tokens[0].text)
+		}
+
+		if existing, found := rules.groups[strings.ToLower(name)]; found {
+			return invalid("group '@@%s' is already defined on line %d", name, existing.line)
+		}
+
+		group := &bitbucketGroup{name: name, line: lineNumber}
+		for _, memberToken := range tokens[1:] {
+			group.members = append(group.members, memberToken.text)
+			group.memberColumns = append(group.memberColumns, memberToken.column)
+		}
+
+		rules.groups[strings.ToLower(name)] = group
+
+		if len(group.members) == 0 {
+			return true, &Diagnostic{
+				Line:     lineNumber,
+				Column:   column,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("group '@@%s' has no members", name),
+			}
+		}
+
+		return true, nil
+	case strings.HasPrefix(trimmed, "Check("):
+		check, _, _ := strings.Cut(trimmed, "#")
+		check = strings.TrimRight(check, " \t")
+		match := bitbucketCheckRE.FindStringSubmatch(check)
+
+		var terms [][]int
+		if match != nil {
+			terms = bitbucketCheckTermRE.FindAllStringSubmatchIndex(check, -1)
+		}
+
+		if len(terms) == 0 {
+			return invalid("invalid merge check '%s', expected Check(@@Group >= approvals)", check)
+		}
+
+		for _, term := range terms {
+			rules.checkRefs = append(rules.checkRefs, bitbucketGroupRef{
+				name:   check[term[2]:term[3]],
+				line:   lineNumber,
+				column: column + term[0],
+			})
+		}
+
+		return true, nil
+	case strings.HasPrefix(trimmed, "CODEOWNERS."):
+		// Settings such as CODEOWNERS.destination_branch_pattern don't change who owns a file
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// members returns the owners in the group, expanding the groups it includes
+func (rules *bitbucketRules) members(group *bitbucketGroup, visiting []*bitbucketGroup) ([]string, []Diagnostic) {
+	owners := []string{}
+	diagnostics := []Diagnostic{}
+	visiting = append(visiting, group)
+
+	for i, member := range group.members {
+		if !isGroupRef(member) {
+			if !slices.Contains(owners, member) {
+				owners = append(owners, member)
+			}
+
+			continue
+		}
+
+		included, found := rules.groups[strings.ToLower(member[2:])]
+
+		if !found {
+			diagnostics = append(diagnostics, undefinedGroup(member, group.line, group.memberColumns[i]))
+			continue
+		}
+
+		if slices.Contains(visiting, included) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     group.line,
+				Column:   group.memberColumns[i],
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("group '@@%s' includes itself through '%s'", group.name, member),
+			})
+			continue
+		}
+
+		includedOwners, includedDiagnostics := rules.members(included, visiting)
+		diagnostics = append(diagnostics, includedDiagnostics...)
+
+		for _, owner := range includedOwners {
+			if !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	return owners, diagnostics
+}
+
+// expandGroups replaces the @@Group owners of the rules with the members of the group, and checks
+// that every group that's used is defined. Groups that aren't defined are left on the rule.
+func (rules *bitbucketRules) expandGroups(entries []OwnerEntry) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	// Problems inside a group are reported on its definition, not on every rule using it
+	groupMembers := map[*bitbucketGroup][]string{}
+	for _, group := range rules.groups {
+		members, memberDiagnostics := rules.members(group, nil)
+		groupMembers[group] = members
+		diagnostics = append(diagnostics, memberDiagnostics...)
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+
+		if !slices.ContainsFunc(entry.Owners, isGroupRef) {
+			continue
+		}
+
+		owners := []string{}
+		ownerColumns := []int{}
+		parsedOwners := []Owner{}
+
+		addOwner := func(owner string, column int) {
+			if slices.Contains(owners, owner) {
+				return
+			}
+
+			owners = append(owners, owner)
+			ownerColumns = append(ownerColumns, column)
+
+			// Invalid owners are reported by Lint
+			if parsedOwner, err := ParseOwner(owner); err == nil {
+				parsedOwners = append(parsedOwners, parsedOwner)
+			}
+		}
+
+		for j, owner := range entry.Owners {
+			if !isGroupRef(owner) {
+				addOwner(owner, entry.ownerColumns[j])
+				continue
+			}
+
+			group, found := rules.groups[strings.ToLower(owner[2:])]
+
+			if !found {
+				// Kept as written so a typo in a group name doesn't take the files away from their owners
+				diagnostics = append(diagnostics, undefinedGroup(owner, entry.Line, entry.ownerColumns[j]))
+				addOwner(owner, entry.ownerColumns[j])
+				continue
+			}
+
+			for _, member := range groupMembers[group] {
+				addOwner(member, entry.ownerColumns[j])
+			}
+		}
+
+		entry.Owners = owners
+		entry.ownerColumns = ownerColumns
+		entry.parsedOwners = parsedOwners
+	}
+
+	for _, ref := range rules.checkRefs {
+		if _, found := rules.groups[strings.ToLower(ref.name)]; !found {
+			diagnostics = append(diagnostics, undefinedGroup("@@"+ref.name, ref.line, ref.column))
+		}
+	}
+
+	// Groups that include other groups find the same problems again
+	slices.SortFunc(diagnostics, compareDiagnostics)
+	return slices.Compact(diagnostics)
+}
+
+// isGroupRef reports whether the owner refers to a group as @@Group
+func isGroupRef(owner string) bool {
+	return strings.HasPrefix(owner, "@@") && !strings.HasPrefix(owner, "@@@")
+}
+
+func undefinedGroup(ref string, line int, column int) Diagnostic {
+	return Diagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("group '%s' is not defined, define it with a '@%s' line", ref, ref),
+	}
+}
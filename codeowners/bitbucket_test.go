@@ -0,0 +1,84 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bitbucketCodeowners = `CODEOWNERS.destination_branch_pattern main
+@@@Backend @octocat @hubot
+@@@Everyone @@Backend @org/docs
+
+* @@Everyone
+app/ @@backend @octocat
+docs/ @org/docs
+
+Check(@@Backend >= 2)
+`
+
+func TestFromReaderDialect_bitbucket(t *testing.T) {
+	codeowners, err := FromReaderDialect(bytes.NewBufferString(bitbucketCodeowners), DialectBitbucket)
+
+	assert.NoError(t, err)
+	assert.Empty(t, codeowners.Diagnostics())
+
+	// Groups are replaced by their members, including the members of groups they include
+	assert.Equal(t, []string{"@octocat", "@hubot", "@org/docs"}, codeowners.FindOwners([]byte("README.md")))
+	assert.Equal(t, []string{"@octocat", "@hubot"}, codeowners.FindOwners([]byte("app/main.go")))
+	assert.Equal(t, []string{"@org/docs"}, codeowners.FindOwners([]byte("docs/index.md")))
+	assert.True(t, codeowners.IsOwnedBy([]byte("app/main.go"), "@Hubot"))
+
+	// Settings, groups and checks aren't rules
+	assert.Equal(t, 5, codeowners.Match([]byte("README.md")).Line)
+	assert.Empty(t, Lint(codeowners, LintOptions{}))
+}
+
+func TestFromReaderDialect_bitbucketDiagnostics(t *testing.T) {
+	contents := `@@@Loop @@Cycle
+@@@Cycle @@Loop
+@@@Backend @octocat
+@@@backend @hubot
+@@@Empty
+app/ @@Frontend @octocat
+Check(@@Backend >= 2 & @@Missing >= 1)
+Check(@@Backend)
+`
+
+	codeowners, err := FromReaderDialect(bytes.NewBufferString(contents), DialectBitbucket)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Line: 1, Column: 9, Severity: SeverityError, Message: "group '@@Loop' includes itself through '@@Cycle'"},
+		{Line: 2, Column: 10, Severity: SeverityError, Message: "group '@@Cycle' includes itself through '@@Loop'"},
+		{Line: 4, Column: 1, Severity: SeverityError, Message: "group '@@backend' is already defined on line 3"},
+		{Line: 5, Column: 1, Severity: SeverityWarning, Message: "group '@@Empty' has no members"},
+		{Line: 6, Column: 6, Severity: SeverityError, Message: "group '@@Frontend' is not defined, define it with a '@@@Frontend' line"},
+		{Line: 7, Column: 24, Severity: SeverityError, Message: "group '@@Missing' is not defined, define it with a '@@@Missing' line"},
+		{Line: 8, Column: 1, Severity: SeverityError, Message: "invalid merge check 'Check(@@Backend)', expected Check(@@Group >= approvals)"},
+	}, codeowners.Diagnostics())
+
+	// The rule keeps the owners that are defined, and the group that isn't as written
+	assert.Equal(t, []string{"@@Frontend", "@octocat"}, codeowners.FindOwners([]byte("app/main.go")))
+}
+
+func TestFromReaderDialect_bitbucketUndefinedGroupKeepsOwnership(t *testing.T) {
+	codeowners, err := FromReaderDialect(bytes.NewBufferString("@@@Backend @octocat\n* @@Backend\napp/ @@Frontend\n"), DialectBitbucket)
+
+	assert.NoError(t, err)
+
+	// The rule doesn't clear the owners like a rule without owners would
+	assert.Equal(t, []string{"@@Frontend"}, codeowners.FindOwners([]byte("app/main.go")))
+	assert.False(t, codeowners.IsIntentionallyUnowned([]byte("app/main.go")))
+
+	// Reported once, not again as a malformed owner
+	assert.Equal(t, []Diagnostic{
+		{Line: 3, Column: 6, Severity: SeverityError, Message: "group '@@Frontend' is not defined, define it with a '@@@Frontend' line"},
+	}, Lint(codeowners, LintOptions{}))
+}
+
+func TestDetectDialect_bitbucket(t *testing.T) {
+	assert.Equal(t, DialectBitbucket, DetectDialect([]byte(bitbucketCodeowners)))
+	assert.Equal(t, DialectBitbucket, DetectDialect([]byte("* @octocat\nCheck(@@Backend >= 1)\n")))
+}
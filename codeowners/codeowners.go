@@ -9,80 +9,373 @@ import (
 	"strings"
 )
 
+// OwnerEntry is a single rule from a CODEOWNERS file
 type OwnerEntry struct {
-	file    string
-	owners  []string
-	matcher regexp.Regexp
+	// 1-based line number the rule was declared on
+	Line int
+	// Pattern as it was written in the file, including any escapes
+	Pattern string
+	// Empty when the rule makes the paths it matches unowned, overriding earlier rules
+	Owners []string
+	// Inline comment text without the leading '#', empty if there wasn't one
+	Comment string
+	// Section the rule is in, the default section for GitHub files and rules before any section header
+	Section *Section
+	// The rule didn't list owners, they come from the section's default owners
+	DefaultOwners bool
+	matcher       regexp.Regexp
+
+	patternColumn int
+	ownerColumns  []int
+	parsedOwners  []Owner
+}
+
+func (entry *OwnerEntry) String() string {
+	rule := entry.Pattern
+
+	if !entry.DefaultOwners {
+		rule = strings.Join(append([]string{entry.Pattern}, entry.Owners...), " ")
+	}
+
+	if entry.Comment != "" {
+		rule += " # " + entry.Comment
+	}
+
+	return rule
+}
+
+// ParsedOwners returns the owners of the rule that are a valid @user, @org/team or email address,
+// GitHub ignores the rest.
+func (entry *OwnerEntry) ParsedOwners() []Owner {
+	return entry.parsedOwners
 }
 
 type Codeowners struct {
-	entries []OwnerEntry
+	entries     []OwnerEntry
+	diagnostics []Diagnostic
+	index       *matcher
+	// Number of lines in the file, not counting the empty line after a trailing newline. Rules built
+	// from OWNERS files have no file, they are numbered as the lines of the CODEOWNERS file
+	// GenerateCodeowners writes without a header, one line per rule.
+	lines   int
+	dialect Dialect
+	// Sections in the order they are first declared, starting with the default section
+	sections []*Section
+	headers  []sectionHeader
+}
+
+// Match returns the rule that decides the owners of the given file, which is the last rule in
+// the file that matches it. When the file has sections, each section has its own winning rule
+// and this returns the one from the first section, use MatchSections to get all of them.
+// Returns nil if no rule matches.
+func (co *Codeowners) Match(fileName []byte) *OwnerEntry {
+	if len(co.sections) > 1 {
+		matches := co.MatchSections(fileName)
+
+		if len(matches) == 0 {
+			return nil
+		}
+
+		return matches[0].Entry
+	}
+
+	matched := co.index.match(fileName, false)
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	return &co.entries[matched[0]]
 }
 
+// MatchSections returns the rule that wins in each section that has a rule matching the given file,
+// in the order the sections are declared.
+func (co *Codeowners) MatchSections(fileName []byte) []SectionMatch {
+	if len(co.sections) == 1 {
+		if entry := co.Match(fileName); entry != nil {
+			return []SectionMatch{{Section: entry.Section, Entry: entry}}
+		}
+
+		return []SectionMatch{}
+	}
+
+	matches := []SectionMatch{}
+
+	// Rules are in match order, so the first rule seen for each section is the one that wins it
+	for _, index := range co.index.match(fileName, true) {
+		entry := &co.entries[index]
+
+		if !slices.ContainsFunc(matches, func(match SectionMatch) bool { return match.Section == entry.Section }) {
+			matches = append(matches, SectionMatch{Section: entry.Section, Entry: entry})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b SectionMatch) int {
+		return a.Section.order - b.Section.order
+	})
+
+	return matches
+}
+
+// MatchAll returns every rule that matches the given file, starting with the rule that wins
+// and followed by the rules it overrides.
+func (co *Codeowners) MatchAll(fileName []byte) []*OwnerEntry {
+	matches := []*OwnerEntry{}
+
+	for _, index := range co.index.match(fileName, true) {
+		matches = append(matches, &co.entries[index])
+	}
+
+	return matches
+}
+
+// FindOwners returns the owners of the given file, which is empty when no rule matches it or
+// the rule that matches it has no owners. When the file has sections these are the owners from
+// every section.
 func (co *Codeowners) FindOwners(fileName []byte) []string {
-	for _, entry := range co.entries {
-		if entry.matcher.Match(fileName) {
-			return entry.owners
+	if len(co.sections) > 1 {
+		owners := []string{}
+
+		for _, match := range co.MatchSections(fileName) {
+			for _, owner := range match.Entry.Owners {
+				if !slices.Contains(owners, owner) {
+					owners = append(owners, owner)
+				}
+			}
 		}
+
+		return owners
+	}
+
+	if entry := co.Match(fileName); entry != nil {
+		return entry.Owners
 	}
 
 	return []string{}
 }
 
+// FindParsedOwners returns the valid owners of the given file, from every section when the file has
+// sections. Owners that only differ by case are only included once.
+func (co *Codeowners) FindParsedOwners(fileName []byte) []Owner {
+	owners := []Owner{}
+
+	for _, match := range co.MatchSections(fileName) {
+		for _, owner := range match.Entry.parsedOwners {
+			if !slices.ContainsFunc(owners, owner.Equal) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	return owners
+}
+
+// IsOwnedBy reports whether the given owner is one of the owners of the file. Owners are compared
+// ignoring case the same way GitHub does, so "@Org/Team" is the same owner as "@org/team".
 func (co *Codeowners) IsOwnedBy(fileName []byte, owner string) bool {
-	return slices.Contains(co.FindOwners(fileName), owner)
+	parsedOwner, err := ParseOwner(owner)
+
+	if err != nil {
+		return slices.Contains(co.FindOwners(fileName), owner)
+	}
+
+	return slices.ContainsFunc(co.FindParsedOwners(fileName), parsedOwner.Equal)
+}
+
+// IsIntentionallyUnowned reports whether the file is matched by rules without owners, as opposed
+// to not being matched by any rule at all.
+func (co *Codeowners) IsIntentionallyUnowned(fileName []byte) bool {
+	return co.Match(fileName) != nil && len(co.FindOwners(fileName)) == 0
+}
+
+// Diagnostics returns the problems found while parsing, in line order.
+func (co *Codeowners) Diagnostics() []Diagnostic {
+	return co.diagnostics
+}
+
+// Dialect returns the dialect the file was parsed as
+func (co *Codeowners) Dialect() Dialect {
+	return co.dialect
 }
 
+// Sections returns the sections in the order they are first declared, starting with the default
+// section. GitHub files only have the default section.
+func (co *Codeowners) Sections() []*Section {
+	return co.sections
+}
+
+// FromReader parses a GitHub CODEOWNERS file. Malformed lines do not fail the parse, they are
+// skipped and reported through Diagnostics so the rest of the rules stay usable. An error
+// is only returned if the reader itself fails.
 func FromReader(reader io.Reader) (*Codeowners, error) {
-	scanner := bufio.NewScanner(reader)
+	return FromReaderDialect(reader, DialectGitHub)
+}
+
+// FromReaderDialect parses a CODEOWNERS file written in the given dialect, the same way as FromReader.
+// A Gerrit OWNERS file is read as the only OWNERS file in the repository, at its root.
+func FromReaderDialect(reader io.Reader, dialect Dialect) (*Codeowners, error) {
+	if dialect == DialectGerrit {
+		contents, err := io.ReadAll(reader)
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading OWNERS: %w", err)
+		}
+
+		return FromOwnersFiles([]OwnersFile{{Path: "OWNERS", Contents: contents}})
+	}
+
+	// Use a reader instead of a scanner so long lines aren't limited by the scanner's max token size
+	bufReader := bufio.NewReader(reader)
 
 	ownerEntries := []OwnerEntry{}
+	diagnostics := []Diagnostic{}
+	lineCount := 0
+
+	defaultSection := &Section{Approvals: 1}
+	sections := []*Section{defaultSection}
+	headers := []sectionHeader{}
+	var currentHeader *sectionHeader
+	bitbucket := &bitbucketRules{groups: map[string]*bitbucketGroup{}}
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := bufReader.ReadString('\n')
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip comments
-		if strings.HasPrefix(line, "#") {
-			continue
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading CODEOWNERS line %d: %w", lineNumber, err)
 		}
 
-		// TODO: More whitespace allowed?
-		splitLine := strings.Split(line, " ")
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
 
-		if len(splitLine) == 0 {
-			continue
+		// Tolerate a UTF-8 byte order mark from editors on Windows
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
 		}
 
-		if len(splitLine) == 1 {
-			// fmt.Printf("Skipping line '%s' as it is improperly formatted.\n", line)
-			continue
+		var entry *OwnerEntry
+		var diagnostic *Diagnostic
+		isHeader := false
+		isDirective := false
+
+		if dialect == DialectGitLab {
+			var header *sectionHeader
+			header, diagnostic, isHeader = parseSectionHeader(line, lineNumber)
+
+			if header != nil {
+				// Repeating a section's name adds more rules to the same section
+				index := slices.IndexFunc(sections, func(section *Section) bool {
+					return !section.IsDefault() && strings.EqualFold(section.Name, header.section.Name)
+				})
+
+				if index == -1 {
+					header.section.order = len(sections)
+					sections = append(sections, header.section)
+				} else {
+					header.section = sections[index]
+				}
+
+				headers = append(headers, *header)
+				currentHeader = &headers[len(headers)-1]
+			}
 		}
 
-		filePattern := splitLine[0]
+		if dialect == DialectBitbucket {
+			isDirective, diagnostic = bitbucket.parseDirective(line, lineNumber)
+		}
 
-		regex, err := buildPatternRegex(filePattern)
+		if !isHeader && !isDirective {
+			entry, diagnostic = parseLine(line, lineNumber)
+		}
 
-		if err != nil {
-			return nil, fmt.Errorf("could not build regex pattern for '%s' %w", filePattern, err)
+		if entry != nil {
+			entry.Section = defaultSection
+
+			if currentHeader != nil {
+				currentHeader.applyTo(entry)
+			}
+
+			ownerEntries = append(ownerEntries, *entry)
 		}
 
-		// Handle inline comments
-		startOfComment := slices.IndexFunc(splitLine[1:], func(entry string) bool {
-			return strings.HasPrefix(entry, "#")
-		})
+		if diagnostic != nil {
+			diagnostics = append(diagnostics, *diagnostic)
+		}
 
-		var owners []string
+		if err == io.EOF {
+			lineCount = lineNumber
+			if line == "" {
+				lineCount--
+			}
 
-		if startOfComment == -1 {
-			owners = splitLine[1:]
-		} else {
-			owners = splitLine[1:startOfComment]
+			break
 		}
+	}
 
-		ownerEntries = append(ownerEntries, OwnerEntry{file: splitLine[0], owners: owners, matcher: *regex})
+	if dialect == DialectBitbucket {
+		// Groups can be used before they are defined, so they are expanded once the whole file is read
+		diagnostics = append(diagnostics, bitbucket.expandGroups(ownerEntries)...)
+		slices.SortStableFunc(diagnostics, compareDiagnostics)
 	}
 
 	slices.Reverse(ownerEntries)
-	return &Codeowners{entries: ownerEntries}, nil
+	return &Codeowners{
+		entries:     ownerEntries,
+		diagnostics: diagnostics,
+		index:       newMatcher(ownerEntries),
+		lines:       lineCount,
+		dialect:     dialect,
+		sections:    sections,
+		headers:     headers,
+	}, nil
+}
+
+// parseLine parses a single line, returning neither an entry nor a diagnostic for blank and
+// comment lines.
+func parseLine(line string, lineNumber int) (*OwnerEntry, *Diagnostic) {
+	tokens, comment := tokenizeLine(line)
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	filePattern := tokens[0]
+
+	regex, err := buildPatternRegex(filePattern.text)
+
+	if err != nil {
+		return nil, &Diagnostic{
+			Line:     lineNumber,
+			Column:   filePattern.column,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("invalid pattern '%s': %v", filePattern.text, err),
+		}
+	}
+
+	// A pattern without owners is still a rule, it makes the paths it matches unowned
+	owners := make([]string, len(tokens)-1)
+	ownerColumns := make([]int, len(tokens)-1)
+	parsedOwners := []Owner{}
+	for i, ownerToken := range tokens[1:] {
+		owners[i] = ownerToken.text
+		ownerColumns[i] = ownerToken.column
+
+		// Invalid owners are reported by Lint
+		if owner, err := ParseOwner(ownerToken.text); err == nil {
+			parsedOwners = append(parsedOwners, owner)
+		}
+	}
+
+	return &OwnerEntry{
+		Line:    lineNumber,
+		Pattern: filePattern.text,
+		Owners:  owners,
+		Comment: comment,
+		matcher: *regex,
+
+		patternColumn: filePattern.column,
+		ownerColumns:  ownerColumns,
+		parsedOwners:  parsedOwners,
+	}, nil
 }
 
 // For more examples of using go-gh, see:
@@ -101,32 +394,16 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 		return regexp.Compile(`\A\z`)
 	}
 
-	segs := strings.Split(pattern, "/")
-
-	if segs[0] == "" {
-		// Leading slash: match is relative to root
-		segs = segs[1:]
-	} else {
-		// No leading slash - check for a single segment pattern, which matches
-		// relative to any descendent path (equivalent to a leading **/)
-		if len(segs) == 1 || (len(segs) == 2 && segs[1] == "") {
-			if segs[0] != "**" {
-				segs = append([]string{"**"}, segs...)
-			}
-		}
-	}
-
-	if len(segs) > 1 && segs[len(segs)-1] == "" {
-		// Trailing slash is equivalent to "/**"
-		segs[len(segs)-1] = "**"
-	}
+	// Shared with the matcher so both always agree on what the segments are
+	segs := normalizeSegments(pattern)
 
 	sep := "/"
 
 	lastSegIndex := len(segs) - 1
 	needSlash := false
 	var re strings.Builder
-	re.WriteString(`\A`)
+	// Paths are opaque, let '.' match every byte including newlines
+	re.WriteString(`(?s)\A`)
 	for i, seg := range segs {
 		switch seg {
 		case "**":
@@ -2,6 +2,8 @@ package codeowners
 
 import (
 	"bytes"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,3 +19,153 @@ test-dir/test @team-1
 
 	assert.True(t, codeowners.IsOwnedBy([]byte("test-dir/test/file.txt"), "@team-1"))
 }
+
+func TestFromReader_whitespaceAndComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		file     string
+		expected []string
+	}{
+		{
+			name:     "Tabs",
+			contents: "docs/\t@team-1\t@team-2\n",
+			file:     "docs/readme.md",
+			expected: []string{"@team-1", "@team-2"},
+		},
+		{
+			name:     "Runs of whitespace",
+			contents: "  docs/    @team-1 \t  @team-2   \n",
+			file:     "docs/readme.md",
+			expected: []string{"@team-1", "@team-2"},
+		},
+		{
+			name:     "Escaped space",
+			contents: "my\\ docs/ @team-1\n",
+			file:     "my docs/readme.md",
+			expected: []string{"@team-1"},
+		},
+		{
+			name:     "Inline comment",
+			contents: "docs/ @team-1 # owned by docs\n",
+			file:     "docs/readme.md",
+			expected: []string{"@team-1"},
+		},
+		{
+			name:     "CRLF",
+			contents: "# Comment\r\ndocs/ @team-1\r\n",
+			file:     "docs/readme.md",
+			expected: []string{"@team-1"},
+		},
+		{
+			name:     "No trailing newline",
+			contents: "docs/ @team-1",
+			file:     "docs/readme.md",
+			expected: []string{"@team-1"},
+		},
+		{
+			name:     "Long line",
+			contents: "docs/ @team-1 " + strings.Repeat("@team-2 ", 20_000) + "\n",
+			file:     "docs/readme.md",
+			expected: append([]string{"@team-1"}, slices.Repeat([]string{"@team-2"}, 20_000)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codeowners, err := FromReader(bytes.NewBufferString(tt.contents))
+
+			assert.NoError(t, err)
+			assert.Empty(t, codeowners.Diagnostics())
+			assert.Equal(t, tt.expected, codeowners.FindOwners([]byte(tt.file)))
+		})
+	}
+}
+
+func TestFromReader_diagnostics(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @default
+docs/*** @team-1
+src/ @team-2
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Line: 2, Column: 1, Message: "invalid pattern 'docs/***': pattern cannot contain three consecutive asterisks"},
+	}, codeowners.Diagnostics())
+
+	// The valid rules are still enforced
+	assert.Equal(t, []string{"@team-2"}, codeowners.FindOwners([]byte("src/main.go")))
+	assert.Equal(t, []string{"@default"}, codeowners.FindOwners([]byte("docs/readme.md")))
+}
+
+func TestMatch(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @default
+docs/ @docs-team # Technical writers
+docs/internal/ @team-1 @team-2
+`))
+
+	assert.NoError(t, err)
+
+	entry := codeowners.Match([]byte("docs/internal/design.md"))
+	assert.NotNil(t, entry)
+	assert.Equal(t, 3, entry.Line)
+	assert.Equal(t, "docs/internal/", entry.Pattern)
+	assert.Equal(t, []string{"@team-1", "@team-2"}, entry.Owners)
+
+	entry = codeowners.Match([]byte("docs/readme.md"))
+	assert.NotNil(t, entry)
+	assert.Equal(t, "Technical writers", entry.Comment)
+	assert.Equal(t, "docs/ @docs-team # Technical writers", entry.String())
+
+	matches := codeowners.MatchAll([]byte("docs/internal/design.md"))
+	lines := []int{}
+	for _, match := range matches {
+		lines = append(lines, match.Line)
+	}
+	assert.Equal(t, []int{3, 2, 1}, lines)
+
+	empty, err := FromReader(bytes.NewBufferString("docs/ @docs-team\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, empty.Match([]byte("src/main.go")))
+	assert.Empty(t, empty.MatchAll([]byte("src/main.go")))
+}
+
+func TestFindOwners_ownerlessRule(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @default
+/docs/ @docs
+/docs/generated/
+/docs/generated/api/ @api # Hand written
+`))
+
+	assert.NoError(t, err)
+	assert.Empty(t, codeowners.Diagnostics())
+
+	// The rule without owners wins over the broader rules
+	entry := codeowners.Match([]byte("docs/generated/index.html"))
+	assert.Equal(t, 3, entry.Line)
+	assert.Equal(t, "/docs/generated/", entry.String())
+	assert.Empty(t, codeowners.FindOwners([]byte("docs/generated/index.html")))
+	assert.True(t, codeowners.IsIntentionallyUnowned([]byte("docs/generated/index.html")))
+
+	// And later rules can still give ownership back
+	assert.Equal(t, []string{"@api"}, codeowners.FindOwners([]byte("docs/generated/api/index.html")))
+	assert.False(t, codeowners.IsIntentionallyUnowned([]byte("docs/generated/api/index.html")))
+
+	unmatched, err := FromReader(bytes.NewBufferString("/docs/ @docs\n"))
+	assert.NoError(t, err)
+	assert.False(t, unmatched.IsIntentionallyUnowned([]byte("src/main.go")))
+}
+
+func TestFindOwners_unusualPaths(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @default
+/assets/日本語/ @team-l10n
+docs/user\ guide/ @team-docs
+`))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"@team-l10n"}, codeowners.FindOwners([]byte("assets/日本語/ファイル.png")))
+	assert.Equal(t, []string{"@team-docs"}, codeowners.FindOwners([]byte("docs/user guide/\"quoted\" name.md")))
+	assert.Equal(t, []string{"@default"}, codeowners.FindOwners([]byte("line\nbreak/file.txt")))
+	assert.Equal(t, []string{"@team-l10n"}, codeowners.FindOwners([]byte("assets/日本語/new\nline.txt")))
+}
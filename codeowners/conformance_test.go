@@ -0,0 +1,227 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Pattern semantics GitHub documents for CODEOWNERS, which follow .gitignore except that !, [ ] and a
+// trailing /* don't work the gitignore way. See
+// https://docs.github.com/en/repositories/managing-your-repositorys-settings-and-features/customizing-your-repository/about-code-owners#codeowners-syntax
+var conformanceTests = []struct {
+	name    string
+	pattern string
+	matches []string
+	misses  []string
+}{
+	{
+		name:    "Everything",
+		pattern: "*",
+		matches: []string{"README.md", "docs/index.md", "a/b/c/d.go", ".github/CODEOWNERS"},
+	},
+	{
+		name:    "Extension anywhere",
+		pattern: "*.js",
+		matches: []string{"app.js", "src/app.js", "src/lib/util.test.js"},
+		misses:  []string{"app.jsx", "app.js.map", "js"},
+	},
+	{
+		name:    "Root directory and everything under it",
+		pattern: "/build/logs/",
+		matches: []string{"build/logs/out.txt", "build/logs/2024/01/out.txt"},
+		misses:  []string{"build/logs", "src/build/logs/out.txt", "build/logsmore/out.txt"},
+	},
+	{
+		name:    "Trailing /* only matches files directly in the directory",
+		pattern: "docs/*",
+		matches: []string{"docs/getting-started.md", "docs/.nojekyll"},
+		misses:  []string{"docs/build-app/troubleshooting.md", "docs", "src/docs/getting-started.md"},
+	},
+	{
+		name:    "Trailing /** matches everything in the directory",
+		pattern: "docs/**",
+		matches: []string{"docs/getting-started.md", "docs/build-app/troubleshooting.md"},
+		misses:  []string{"docs", "src/docs/getting-started.md"},
+	},
+	{
+		name:    "Root /* only matches files at the root",
+		pattern: "/*",
+		matches: []string{"README.md", "go.mod"},
+		misses:  []string{"docs/index.md"},
+	},
+	{
+		name:    "Root /** matches everything",
+		pattern: "/**",
+		matches: []string{"README.md", "docs/index.md"},
+	},
+	{
+		name:    "Repeated ** is the same as one",
+		pattern: "**/**",
+		matches: []string{"README.md", "docs/index.md"},
+	},
+	{
+		name:    "Every directory",
+		pattern: "**/",
+		matches: []string{"docs/index.md", "a/b/c.go"},
+		misses:  []string{"README.md"},
+	},
+	{
+		name:    "Every directory from the root",
+		pattern: "/**/",
+		matches: []string{"docs/index.md", "a/b/c.go"},
+		misses:  []string{"README.md"},
+	},
+	{
+		name:    "Repeated ** between directories",
+		pattern: "/src/**/**/test/",
+		matches: []string{"src/test/a.go", "src/a/b/test/c.go"},
+		misses:  []string{"test/a.go"},
+	},
+	{
+		name:    "Directory anywhere",
+		pattern: "apps/",
+		matches: []string{"apps/main.go", "apps/web/index.js", "src/apps/main.go", "a/b/apps/c/d.go"},
+		misses:  []string{"apps", "src/apps", "myapps/main.go"},
+	},
+	{
+		name:    "Root directory",
+		pattern: "/docs/",
+		matches: []string{"docs/index.md", "docs/api/v1.md"},
+		misses:  []string{"src/docs/index.md", "docs"},
+	},
+	{
+		name:    "Directory anywhere with a leading **",
+		pattern: "**/logs",
+		matches: []string{"logs", "logs/out.txt", "build/logs/out.txt", "scripts/logs/out.txt", "deeply/nested/logs/out.txt"},
+		misses:  []string{"build/logsmore/out.txt", "build/mylogs"},
+	},
+	{
+		name:    "Name anywhere, file or directory",
+		pattern: "logs",
+		matches: []string{"logs", "logs/out.txt", "build/logs", "build/logs/out.txt"},
+		misses:  []string{"build/logs.txt", "catalogs"},
+	},
+	{
+		name:    "Root path without a trailing slash",
+		pattern: "/apps/github",
+		matches: []string{"apps/github", "apps/github/main.go"},
+		misses:  []string{"apps/github-app/main.go", "src/apps/github/main.go"},
+	},
+	{
+		name:    "Multiple segments without a leading slash are relative to the root",
+		pattern: "apps/github",
+		matches: []string{"apps/github", "apps/github/main.go"},
+		misses:  []string{"src/apps/github/main.go", "apps/github-app/main.go"},
+	},
+	{
+		name:    "Multiple segments with a trailing slash are relative to the root",
+		pattern: "build/logs/",
+		matches: []string{"build/logs/out.txt", "build/logs/2024/out.txt"},
+		misses:  []string{"src/build/logs/out.txt", "build/logs"},
+	},
+	{
+		name:    "Wildcard file name in a directory",
+		pattern: "docs/*.md",
+		matches: []string{"docs/index.md"},
+		misses:  []string{"docs/api/index.md", "src/docs/index.md", "docs/index.txt"},
+	},
+	{
+		name:    "** between directories matches any number of them",
+		pattern: "src/**/test/",
+		matches: []string{"src/test/a.go", "src/pkg/test/a.go", "src/a/b/c/test/d/e.go"},
+		misses:  []string{"test/a.go", "lib/src/test/a.go", "src/test"},
+	},
+	{
+		name:    "* as a directory segment matches exactly one directory",
+		pattern: "src/*/internal/",
+		matches: []string{"src/pkg/internal/a.go", "src/pkg/internal/b/c.go"},
+		misses:  []string{"src/internal/a.go", "src/a/b/internal/c.go", "lib/src/pkg/internal/a.go"},
+	},
+	{
+		name:    "* inside a directory segment",
+		pattern: "/services/*-api/",
+		matches: []string{"services/billing-api/main.go", "services/billing-api/pkg/a.go"},
+		misses:  []string{"services/billing/main.go", "services/billing-api", "services/x/billing-api/main.go"},
+	},
+	{
+		name:    "Leading * segment",
+		pattern: "*/docs/",
+		matches: []string{"src/docs/index.md"},
+		misses:  []string{"docs/index.md", "a/b/docs/index.md"},
+	},
+	{
+		name:    "? matches a single character",
+		pattern: "/v?/",
+		matches: []string{"v1/api.go", "v2/a/b.go"},
+		misses:  []string{"v10/api.go", "v/api.go"},
+	},
+	{
+		name:    "Escaped # at the start",
+		pattern: `\#notes`,
+		matches: []string{"#notes", "docs/#notes", "#notes/todo.md"},
+		misses:  []string{"notes", `\#notes`},
+	},
+	{
+		name:    "Escaped # in a directory",
+		pattern: `/docs/\#drafts/`,
+		matches: []string{"docs/#drafts/todo.md"},
+		misses:  []string{"docs/drafts/todo.md"},
+	},
+	{
+		name:    "Escaped space",
+		pattern: `/my\ docs/`,
+		matches: []string{"my docs/index.md"},
+		misses:  []string{"my/index.md"},
+	},
+	{
+		name:    "Escaped wildcard is literal",
+		pattern: `/docs/\*.md`,
+		matches: []string{"docs/*.md"},
+		misses:  []string{"docs/index.md"},
+	},
+	{
+		name:    "Paths are case sensitive",
+		pattern: "/Docs/",
+		matches: []string{"Docs/index.md"},
+		misses:  []string{"docs/index.md"},
+	},
+	{
+		name:    "Dots are literal",
+		pattern: "/.github/",
+		matches: []string{".github/CODEOWNERS"},
+		misses:  []string{"xgithub/CODEOWNERS"},
+	},
+	{
+		name:    "Nothing matches a lone slash",
+		pattern: "/",
+		misses:  []string{"README.md", "docs/index.md"},
+	},
+}
+
+func TestPatternConformance(t *testing.T) {
+	for _, test := range conformanceTests {
+		t.Run(test.name, func(t *testing.T) {
+			codeowners, err := FromReader(bytes.NewBufferString(test.pattern + " @owner\n"))
+			assert.NoError(t, err)
+			assert.Empty(t, codeowners.Diagnostics())
+
+			regex, err := buildPatternRegex(test.pattern)
+			assert.NoError(t, err)
+
+			check := func(path string, expected bool) {
+				assert.Equal(t, expected, regex.MatchString(path), "regex for '%s' on '%s'", test.pattern, path)
+				assert.Equal(t, expected, codeowners.Match([]byte(path)) != nil, "matcher for '%s' on '%s'", test.pattern, path)
+			}
+
+			for _, path := range test.matches {
+				check(path, true)
+			}
+
+			for _, path := range test.misses {
+				check(path, false)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Document is a CODEOWNERS file as it is written, line by line, so it can be changed and written back
+// out without losing comments, blank lines or lines that aren't rules.
+type Document struct {
+	Lines   []*DocumentLine
+	dialect Dialect
+	// Written back out as they were read, so an edit only changes the lines it touches
+	lineEnding string
+	bom        bool
+}
+
+// DocumentLine is a single line of a Document
+type DocumentLine struct {
+	// The line as it is written out
+	Text string
+	// The rule declared on the line, nil for blank lines, comments, section headers and lines that don't parse
+	Rule *DocumentRule
+
+	// Owners listed on lines that aren't rules: section default owners and Bitbucket group members
+	owners          []token
+	isSectionHeader bool
+}
+
+// DocumentRule is a rule as it is written, before its owners are checked
+type DocumentRule struct {
+	// Pattern as it is written, including any escapes
+	Pattern string
+	Owners  []string
+	// Inline comment text without the leading '#'
+	Comment string
+}
+
+// String writes the rule with a single space between each part
+func (rule *DocumentRule) String() string {
+	return rule.pad(0)
+}
+
+// pad writes the rule with the owners starting at least the given number of bytes after the start of the pattern
+func (rule *DocumentRule) pad(width int) string {
+	var text strings.Builder
+	text.WriteString(rule.Pattern)
+
+	if len(rule.Owners) > 0 {
+		text.WriteString(strings.Repeat(" ", max(width-len(rule.Pattern), 1)))
+		text.WriteString(strings.Join(rule.Owners, " "))
+	}
+
+	if rule.Comment != "" {
+		text.WriteString(" # ")
+		text.WriteString(rule.Comment)
+	}
+
+	return text.String()
+}
+
+// ParseDocument reads a CODEOWNERS file written in the given dialect into a Document. Lines that
+// aren't valid rules are kept as they are. Gerrit OWNERS files aren't CODEOWNERS files and can't be
+// read as a Document.
+func ParseDocument(reader io.Reader, dialect Dialect) (*Document, error) {
+	if dialect == DialectGerrit {
+		return nil, fmt.Errorf("OWNERS files can't be edited as a CODEOWNERS file")
+	}
+
+	bufReader := bufio.NewReader(reader)
+	document := &Document{dialect: dialect, lineEnding: "\n"}
+	bitbucket := &bitbucketRules{groups: map[string]*bitbucketGroup{}}
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := bufReader.ReadString('\n')
+
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading CODEOWNERS line %d: %w", lineNumber, err)
+		}
+
+		if err == io.EOF && line == "" {
+			break
+		}
+
+		if lineNumber == 1 {
+			// The first line decides the line ending of the whole file
+			if strings.HasSuffix(line, "\r\n") {
+				document.lineEnding = "\r\n"
+			}
+
+			document.bom = strings.HasPrefix(line, "\ufeff")
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+
+		document.Lines = append(document.Lines, parseDocumentLine(line, lineNumber, dialect, bitbucket))
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return document, nil
+}
+
+// parseDocumentLine reads a single line, only lines that parse as a rule in the dialect get a Rule
+func parseDocumentLine(line string, lineNumber int, dialect Dialect, bitbucket *bitbucketRules) *DocumentLine {
+	documentLine := &DocumentLine{Text: line}
+
+	if dialect == DialectGitLab {
+		if header, _, isHeader := parseSectionHeader(line, lineNumber); isHeader {
+			documentLine.isSectionHeader = true
+
+			if header != nil {
+				for i, owner := range header.defaultOwners {
+					documentLine.owners = append(documentLine.owners, token{text: owner, column: header.ownerColumns[i]})
+				}
+			}
+
+			return documentLine
+		}
+	}
+
+	if dialect == DialectBitbucket {
+		if isDirective, _ := bitbucket.parseDirective(line, lineNumber); isDirective {
+			if strings.HasPrefix(strings.TrimLeft(line, " \t"), "@@@") {
+				tokens, _ := tokenizeLine(line)
+				documentLine.owners = tokens[1:]
+			}
+
+			return documentLine
+		}
+	}
+
+	entry, diagnostic := parseLine(line, lineNumber)
+
+	if entry != nil && diagnostic == nil {
+		documentLine.Rule = &DocumentRule{
+			Pattern: entry.Pattern,
+			Owners:  entry.Owners,
+			Comment: entry.Comment,
+		}
+	}
+
+	return documentLine
+}
+
+// Bytes writes the document out, every line ends with a newline. The line ending and byte order
+// mark of the file that was read are kept.
+func (document *Document) Bytes() []byte {
+	var written bytes.Buffer
+
+	if document.bom {
+		written.WriteString("\ufeff")
+	}
+
+	for _, line := range document.Lines {
+		written.WriteString(line.Text)
+		written.WriteString(document.lineEnding)
+	}
+
+	return written.Bytes()
+}
+
+// Codeowners parses the document as it is now, to see which rules own which files
+func (document *Document) Codeowners() (*Codeowners, error) {
+	return FromReaderDialect(bytes.NewReader(document.Bytes()), document.dialect)
+}
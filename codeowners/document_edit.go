@@ -0,0 +1,272 @@
+package codeowners
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DocumentChange is a line added, removed or changed by an edit to a Document
+type DocumentChange struct {
+	// 1-based line number, in the document after the edit for added lines and before the edit otherwise
+	Line int
+	// Text of the line before the edit, empty for added lines
+	Before string
+	// Text of the line after the edit, empty for removed lines
+	After string
+}
+
+// AddRule gives the owners the files matched by the pattern. When there's already a rule for the pattern the
+// owners are added to its last rule. Otherwise a new rule is added at the end of the rules, so it isn't shadowed,
+// but before any rule for files inside the pattern so those keep their owners. It always goes after the rules
+// for the directories the pattern is in. GitLab rules are added to the default section.
+func (document *Document) AddRule(pattern string, owners []string) ([]DocumentChange, error) {
+	entry, diagnostic := parseLine(strings.Join(append([]string{pattern}, owners...), " "), 1)
+
+	if diagnostic != nil {
+		return nil, fmt.Errorf("%s", diagnostic.Message)
+	}
+
+	if entry == nil || entry.Pattern != pattern {
+		return nil, fmt.Errorf("'%s' is not a pattern", pattern)
+	}
+
+	for _, owner := range entry.Owners {
+		if _, err := ParseOwner(owner); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rules after the first section header are in other sections, which don't shadow the default section
+	limit := slices.IndexFunc(document.Lines, func(line *DocumentLine) bool { return line.isSectionHeader })
+	if limit == -1 {
+		limit = len(document.Lines)
+	}
+
+	existing := -1
+	lastContaining := -1
+	lastRule := -1
+
+	for i, line := range document.Lines[:limit] {
+		if line.Rule == nil {
+			continue
+		}
+
+		lastRule = i
+
+		if line.Rule.Pattern == pattern {
+			existing = i
+		}
+
+		if patternContains(line.Rule.Pattern, pattern) {
+			lastContaining = i
+		}
+	}
+
+	if existing != -1 {
+		line := document.Lines[existing]
+		before := line.Text
+		width := line.ownersWidth()
+		line.Rule.Owners = appendParsedOwners(line.Rule.Owners, entry.Owners...)
+		line.Text = line.Rule.pad(width)
+
+		if line.Text == before {
+			return []DocumentChange{}, nil
+		}
+
+		return []DocumentChange{{Line: existing + 1, Before: before, After: line.Text}}, nil
+	}
+
+	position := lastRule + 1
+	if lastRule == -1 {
+		position = limit
+	}
+
+	for i := lastContaining + 1; i <= lastRule; i++ {
+		if rule := document.Lines[i].Rule; rule != nil && patternContains(pattern, rule.Pattern) {
+			position = i
+			break
+		}
+	}
+
+	rule := &DocumentRule{Pattern: pattern, Owners: entry.Owners}
+	added := &DocumentLine{Text: rule.String(), Rule: rule}
+
+	// Line up with the rules around it
+	for _, neighbour := range []int{position - 1, position} {
+		if neighbour >= 0 && neighbour < len(document.Lines) && document.Lines[neighbour].Rule != nil {
+			added.Text = rule.pad(document.Lines[neighbour].ownersWidth())
+			break
+		}
+	}
+
+	document.Lines = slices.Insert(document.Lines, position, added)
+
+	return []DocumentChange{{Line: position + 1, After: added.Text}}, nil
+}
+
+// RemoveRules removes every rule for the pattern, as it is written
+func (document *Document) RemoveRules(pattern string) []DocumentChange {
+	changes := []DocumentChange{}
+
+	lines := []*DocumentLine{}
+	for i, line := range document.Lines {
+		if line.Rule != nil && line.Rule.Pattern == pattern {
+			changes = append(changes, DocumentChange{Line: i + 1, Before: line.Text})
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	document.Lines = lines
+	return changes
+}
+
+// ReplaceOwner replaces an owner with another everywhere it is listed, ignoring case. Lines that already
+// list the new owner just lose the old one. Section default owners and Bitbucket group members are
+// replaced too.
+func (document *Document) ReplaceOwner(from Owner, to Owner) []DocumentChange {
+	changes := []DocumentChange{}
+
+	for i, line := range document.Lines {
+		before := line.Text
+
+		if line.Rule != nil {
+			if !slices.ContainsFunc(line.Rule.Owners, from.matches) {
+				continue
+			}
+
+			width := line.ownersWidth()
+			owners := []string{}
+
+			for _, owner := range line.Rule.Owners {
+				if from.matches(owner) {
+					owner = to.String()
+				}
+
+				owners = appendParsedOwners(owners, owner)
+			}
+
+			line.Rule.Owners = owners
+			line.Text = line.Rule.pad(width)
+		} else {
+			line.replaceListedOwner(from, to)
+		}
+
+		if line.Text != before {
+			changes = append(changes, DocumentChange{Line: i + 1, Before: before, After: line.Text})
+		}
+	}
+
+	return changes
+}
+
+// replaceListedOwner edits the owners listed on a line that isn't a rule in place, keeping the rest of the line as it is
+func (line *DocumentLine) replaceListedOwner(from Owner, to Owner) {
+	alreadyListed := slices.ContainsFunc(line.owners, func(owner token) bool { return to.matches(owner.text) })
+	owners := []token{}
+
+	// Edit from the end so the columns of the owners before each edit stay the same
+	for i := len(line.owners) - 1; i >= 0; i-- {
+		owner := line.owners[i]
+		start := owner.column - 1
+		end := start + len(owner.text)
+
+		if !from.matches(owner.text) {
+			owners = append(owners, owner)
+			continue
+		}
+
+		if alreadyListed {
+			// Take the whitespace before the owner with it
+			start = len(strings.TrimRight(line.Text[:start], " \t"))
+			line.Text = line.Text[:start] + line.Text[end:]
+			continue
+		}
+
+		line.Text = line.Text[:start] + to.String() + line.Text[end:]
+		owners = append(owners, token{text: to.String(), column: owner.column})
+		alreadyListed = true
+	}
+
+	slices.Reverse(owners)
+	line.owners = owners
+}
+
+// ownersWidth returns how far after the start of the pattern the owners of a rule line start, so the
+// rule keeps its alignment when its owners change
+func (line *DocumentLine) ownersWidth() int {
+	rest, found := strings.CutPrefix(line.Text, line.Rule.Pattern)
+
+	if !found || len(line.Rule.Owners) == 0 {
+		return 0
+	}
+
+	return len(line.Rule.Pattern) + len(rest) - len(strings.TrimLeft(rest, " \t"))
+}
+
+// matches reports whether the text is this owner, ignoring case
+func (owner Owner) matches(text string) bool {
+	other, err := ParseOwner(text)
+	return err == nil && owner.Equal(other)
+}
+
+// appendParsedOwners adds the owners that aren't already listed, comparing valid owners ignoring case
+func appendParsedOwners(owners []string, more ...string) []string {
+	for _, owner := range more {
+		parsedOwner, err := ParseOwner(owner)
+
+		listed := slices.ContainsFunc(owners, func(existing string) bool {
+			if err != nil {
+				return existing == owner
+			}
+
+			return parsedOwner.matches(existing)
+		})
+
+		if !listed {
+			owners = append(owners, owner)
+		}
+	}
+
+	return owners
+}
+
+// patternContains reports whether every file the inner pattern can match is also matched by the outer pattern,
+// which must be for a literal directory, like /docs/, or match every file. Patterns that match the same files
+// don't contain each other.
+func patternContains(outer string, inner string) bool {
+	if matchesEverything(outer) {
+		return !matchesEverything(inner)
+	}
+
+	segs := normalizeSegments(outer)
+
+	if segs[len(segs)-1] == "**" {
+		segs = segs[:len(segs)-1]
+	}
+
+	outerPrefix := literalPrefix(outer)
+
+	if len(segs) == 0 || len(outerPrefix) != len(segs) {
+		return false
+	}
+
+	innerSegs := normalizeSegments(inner)
+	innerPrefix := literalPrefix(inner)
+
+	if len(innerPrefix) < len(outerPrefix) || !slices.Equal(innerPrefix[:len(outerPrefix)], outerPrefix) {
+		return false
+	}
+
+	// What's left of the inner pattern has to narrow it down, /docs/** is the same as /docs/
+	rest := innerSegs[len(outerPrefix):]
+	return len(rest) > 0 && !slices.Equal(rest, []string{"**"})
+}
+
+// matchesEverything reports whether the pattern matches every file, like * or /**
+func matchesEverything(pattern string) bool {
+	segs := normalizeSegments(pattern)
+	return slices.Equal(segs, []string{"**"}) || slices.Equal(segs, []string{"**", "*"})
+}
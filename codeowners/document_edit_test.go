@@ -0,0 +1,162 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestDocument(t *testing.T, contents string, dialect Dialect) *Document {
+	document, err := ParseDocument(bytes.NewBufferString(contents), dialect)
+	assert.NoError(t, err)
+	return document
+}
+
+func TestDocumentAddRule(t *testing.T) {
+	document := parseTestDocument(t, "# Default owners\n*       @org/everyone\n/docs/  @org/docs\n\n# Services\n/services/auth/ @org/auth\n*.sql @org/dba\n", DialectGitHub)
+
+	changes, err := document.AddRule("/api/", []string{"@org/api"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 8, After: "/api/ @org/api"}}, changes)
+
+	changes, err = document.AddRule("/services/", []string{"@org/services"})
+
+	// Goes before /services/auth/ so auth keeps its owners, and *.sql still wins for SQL files
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 6, After: "/services/      @org/services"}}, changes)
+
+	changes, err = document.AddRule("/docs/", []string{"@org/writers", "@ORG/docs"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 3, Before: "/docs/  @org/docs", After: "/docs/  @org/docs @org/writers"}}, changes)
+
+	assert.Equal(t, "# Default owners\n*       @org/everyone\n/docs/  @org/docs @org/writers\n\n# Services\n/services/      @org/services\n/services/auth/ @org/auth\n*.sql @org/dba\n/api/ @org/api\n", string(document.Bytes()))
+
+	codeowners, err := document.Codeowners()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"@org/auth"}, codeowners.FindOwners([]byte("services/auth/login.go")))
+	assert.Equal(t, []string{"@org/services"}, codeowners.FindOwners([]byte("services/billing/invoice.go")))
+	assert.Equal(t, []string{"@org/api"}, codeowners.FindOwners([]byte("api/schema.sql")))
+	assert.Equal(t, []string{"@org/dba"}, codeowners.FindOwners([]byte("services/billing/schema.sql")))
+}
+
+func TestDocumentAddRule_alreadyOwned(t *testing.T) {
+	document := parseTestDocument(t, "/docs/ @org/docs\n", DialectGitHub)
+
+	changes, err := document.AddRule("/docs/", []string{"@Org/Docs"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDocumentAddRule_invalid(t *testing.T) {
+	document := parseTestDocument(t, "", DialectGitHub)
+
+	_, err := document.AddRule("/docs/", []string{"not-an-owner"})
+	assert.EqualError(t, err, "owner 'not-an-owner' is not a @user, @org/team or email address")
+
+	_, err = document.AddRule("#docs", []string{"@org/docs"})
+	assert.EqualError(t, err, "'#docs' is not a pattern")
+
+	changes, err := document.AddRule("/docs/", []string{"@org/docs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 1, After: "/docs/ @org/docs"}}, changes)
+}
+
+func TestDocumentAddRule_gitlab(t *testing.T) {
+	document := parseTestDocument(t, "* @org/everyone\n\n[Docs] @org/docs\n/docs/\n", DialectGitLab)
+
+	changes, err := document.AddRule("/api/", []string{"@org/api"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 2, After: "/api/ @org/api"}}, changes)
+	assert.Equal(t, "* @org/everyone\n/api/ @org/api\n\n[Docs] @org/docs\n/docs/\n", string(document.Bytes()))
+}
+
+func TestDocumentRemoveRules(t *testing.T) {
+	document := parseTestDocument(t, "# Docs\n/docs/ @org/docs\n/api/ @org/api\n/docs/ @org/writers # again\n", DialectGitHub)
+
+	changes := document.RemoveRules("/docs/")
+
+	assert.Equal(t, []DocumentChange{
+		{Line: 2, Before: "/docs/ @org/docs"},
+		{Line: 4, Before: "/docs/ @org/writers # again"},
+	}, changes)
+	assert.Equal(t, "# Docs\n/api/ @org/api\n", string(document.Bytes()))
+
+	assert.Empty(t, document.RemoveRules("/docs/"))
+}
+
+func TestDocumentReplaceOwner(t *testing.T) {
+	document := parseTestDocument(t, "# @org/payments owns billing\n/billing/   @Org/Payments @octocat # money\n/invoices/  @org/billing @org/payments\n/docs/      @org/docs\n", DialectGitHub)
+
+	from, _ := ParseOwner("@org/payments")
+	to, _ := ParseOwner("@org/billing")
+
+	changes := document.ReplaceOwner(from, to)
+
+	assert.Equal(t, []DocumentChange{
+		{Line: 2, Before: "/billing/   @Org/Payments @octocat # money", After: "/billing/   @org/billing @octocat # money"},
+		{Line: 3, Before: "/invoices/  @org/billing @org/payments", After: "/invoices/  @org/billing"},
+	}, changes)
+}
+
+func TestDocumentReplaceOwner_listedOwners(t *testing.T) {
+	from, _ := ParseOwner("@org/payments")
+	to, _ := ParseOwner("@org/billing")
+
+	document := parseTestDocument(t, "[Billing][2] @org/payments  @octocat\n/billing/\n", DialectGitLab)
+
+	changes := document.ReplaceOwner(from, to)
+
+	assert.Equal(t, []DocumentChange{{Line: 1, Before: "[Billing][2] @org/payments  @octocat", After: "[Billing][2] @org/billing  @octocat"}}, changes)
+
+	document = parseTestDocument(t, "@@@Billing @octocat @org/billing @org/payments\n/billing/ @@Billing\n", DialectBitbucket)
+
+	changes = document.ReplaceOwner(from, to)
+
+	assert.Equal(t, []DocumentChange{{Line: 1, Before: "@@@Billing @octocat @org/billing @org/payments", After: "@@@Billing @octocat @org/billing"}}, changes)
+}
+
+func TestPatternContains(t *testing.T) {
+	assert.True(t, patternContains("/services/", "/services/auth/"))
+	assert.True(t, patternContains("/services", "/services/*.go"))
+	assert.True(t, patternContains("/services/**", "/services/auth/"))
+	assert.False(t, patternContains("/services/", "/services/"))
+	assert.False(t, patternContains("/services/", "/api/"))
+	assert.False(t, patternContains("services/", "/services/auth/"))
+	assert.False(t, patternContains("/services/*/", "/services/auth/"))
+	assert.False(t, patternContains("/services/", "/services/**"))
+	assert.True(t, patternContains("*", "/services/"))
+	assert.True(t, patternContains("/**", "*.go"))
+	assert.False(t, patternContains("*", "**"))
+}
+
+func TestDocumentAddRule_afterContainingRules(t *testing.T) {
+	document := parseTestDocument(t, "/services/auth/tokens/ @org/tokens\n/services/ @org/services\n", DialectGitHub)
+
+	changes, err := document.AddRule("/services/auth/", []string{"@org/auth"})
+
+	// Has to follow /services/, even though that shadows the tokens rule that was already shadowed
+	assert.NoError(t, err)
+	assert.Equal(t, []DocumentChange{{Line: 3, After: "/services/auth/ @org/auth"}}, changes)
+}
+
+func TestDocument_keepsLineEndings(t *testing.T) {
+	contents := "\ufeff# Default owners\r\n* @org/everyone\r\n\r\n/docs/ @org/docs # Writers\r\n"
+	document := parseTestDocument(t, contents, DialectGitHub)
+
+	assert.Equal(t, contents, string(document.Bytes()))
+
+	_, err := document.AddRule("/api/", []string{"@org/api"})
+
+	// Only the added line is new, the others are written as they were read
+	assert.NoError(t, err)
+	assert.Equal(t, contents+"/api/  @org/api\r\n", string(document.Bytes()))
+
+	codeowners, err := document.Codeowners()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"@org/docs"}, codeowners.FindOwners([]byte("docs/index.md")))
+}
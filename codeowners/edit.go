@@ -0,0 +1,119 @@
+package codeowners
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Clone returns a copy of the rules that can be edited without changing the original.
+func (co *Codeowners) Clone() *Codeowners {
+	entries := slices.Clone(co.entries)
+
+	return &Codeowners{
+		entries:     entries,
+		diagnostics: slices.Clone(co.diagnostics),
+		index:       newMatcher(entries),
+		lines:       co.lines,
+		dialect:     co.dialect,
+		sections:    co.sections,
+		headers:     slices.Clone(co.headers),
+	}
+}
+
+// InsertRule parses the given rule and inserts it as if it was written on the given line of the
+// file, moving that line and everything after it down a line. A line of 0, or past the end of the
+// file, adds the rule to the end of the file where it wins over every other rule.
+func (co *Codeowners) InsertRule(line int, rule string) (*OwnerEntry, error) {
+	if line <= 0 || line > co.lines {
+		line = co.lines + 1
+	}
+
+	entry, diagnostic := parseLine(rule, line)
+
+	if diagnostic != nil {
+		return nil, fmt.Errorf("%s", diagnostic.Message)
+	}
+
+	if entry == nil {
+		return nil, fmt.Errorf("'%s' is not a rule", rule)
+	}
+
+	entry.Section = co.sections[0]
+
+	// The rule joins the section of the last header above it
+	for i := len(co.headers) - 1; i >= 0; i-- {
+		if co.headers[i].line < line {
+			co.headers[i].applyTo(entry)
+			break
+		}
+	}
+
+	entries := slices.Clone(co.entries)
+	co.shiftLines(entries, line, 1)
+
+	// Entries are in match order, so the rule goes after every rule declared below it
+	position := 0
+	for position < len(entries) && entries[position].Line > line {
+		position++
+	}
+
+	co.setEntries(slices.Insert(entries, position, *entry))
+
+	return &co.entries[position], nil
+}
+
+// RemoveRule removes the rule declared on the given line, moving everything after it up a line.
+// Returns the rule that was removed.
+func (co *Codeowners) RemoveRule(line int) (*OwnerEntry, error) {
+	position := slices.IndexFunc(co.entries, func(entry OwnerEntry) bool {
+		return entry.Line == line
+	})
+
+	if position == -1 {
+		return nil, fmt.Errorf("there is no rule on line %d", line)
+	}
+
+	removed := co.entries[position]
+
+	entries := slices.Delete(slices.Clone(co.entries), position, position+1)
+	co.shiftLines(entries, line+1, -1)
+	co.setEntries(entries)
+
+	return &removed, nil
+}
+
+// shiftLines moves the given entries, the section headers and the diagnostics from the given line onwards by the given
+// number of lines
+func (co *Codeowners) shiftLines(entries []OwnerEntry, from int, by int) {
+	for i := range entries {
+		if entries[i].Line >= from {
+			entries[i].Line += by
+		}
+	}
+
+	diagnostics := slices.Clone(co.diagnostics)
+	for i := range diagnostics {
+		// Diagnostics with a path are in the OWNERS files the rules were built from, which aren't changed
+		if diagnostics[i].Path == "" && diagnostics[i].Line >= from {
+			diagnostics[i].Line += by
+		}
+	}
+
+	headers := slices.Clone(co.headers)
+	for i := range headers {
+		if headers[i].line >= from {
+			headers[i].line += by
+		}
+	}
+
+	co.diagnostics = diagnostics
+	co.headers = headers
+	co.lines += by
+}
+
+// setEntries replaces the rules, which must be in match order, and rebuilds the index over them.
+// Entries are never edited in place so entries returned before the change are left as they were.
+func (co *Codeowners) setEntries(entries []OwnerEntry) {
+	co.entries = entries
+	co.index = newMatcher(entries)
+}
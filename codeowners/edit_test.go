@@ -0,0 +1,74 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertRule(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @everyone
+# Services
+/services/ @services
+/services/billing/legacy/ @legacy
+`))
+
+	assert.NoError(t, err)
+
+	proposed := codeowners.Clone()
+
+	// Before the legacy rule so it keeps winning for its own directory
+	entry, err := proposed.InsertRule(4, "/services/billing/ @billing")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, entry.Line)
+
+	assert.Equal(t, []string{"@billing"}, proposed.FindOwners([]byte("services/billing/invoice.go")))
+	assert.Equal(t, []string{"@legacy"}, proposed.FindOwners([]byte("services/billing/legacy/old.go")))
+	assert.Equal(t, 5, proposed.Match([]byte("services/billing/legacy/old.go")).Line)
+
+	// The original is left alone
+	assert.Equal(t, []string{"@services"}, codeowners.FindOwners([]byte("services/billing/invoice.go")))
+	assert.Equal(t, 4, codeowners.Match([]byte("services/billing/legacy/old.go")).Line)
+
+	// Adding to the end wins over everything
+	entry, err = proposed.InsertRule(0, "*.md @docs")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, entry.Line)
+	assert.Equal(t, []string{"@docs"}, proposed.FindOwners([]byte("services/billing/legacy/README.md")))
+}
+
+func TestInsertRule_invalid(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString("* @everyone\n"))
+
+	assert.NoError(t, err)
+
+	_, err = codeowners.InsertRule(0, "/a/***/b @team")
+	assert.EqualError(t, err, "invalid pattern '/a/***/b': pattern cannot contain three consecutive asterisks")
+
+	_, err = codeowners.InsertRule(0, "# just a comment")
+	assert.EqualError(t, err, "'# just a comment' is not a rule")
+}
+
+func TestRemoveRule(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @everyone
+/docs/ @docs
+/docs/api/ @api
+`))
+
+	assert.NoError(t, err)
+
+	removed, err := codeowners.RemoveRule(2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/docs/ @docs", removed.String())
+	assert.Equal(t, []string{"@everyone"}, codeowners.FindOwners([]byte("docs/index.md")))
+
+	// Later rules move up a line
+	assert.Equal(t, 2, codeowners.Match([]byte("docs/api/index.md")).Line)
+
+	_, err = codeowners.RemoveRule(3)
+	assert.EqualError(t, err, "there is no rule on line 3")
+}
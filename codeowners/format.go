@@ -0,0 +1,134 @@
+package codeowners
+
+import (
+	"slices"
+	"strings"
+)
+
+// Format rewrites the document in its canonical form:
+//
+//   - owners are written in lower case and repeated owners are removed
+//   - the owners of a block of rules, lines of rules without a blank line or comment between
+//     them, start in the same column
+//   - rules in a block are sorted by pattern, but only past rules that can't match the same files
+//     so the owners of every file stay the same
+//   - runs of blank lines are collapsed, trailing whitespace is removed
+//
+// Comments, section headers and lines that aren't valid rules are kept where they are.
+func (document *Document) Format() {
+	lines := []*DocumentLine{}
+
+	for _, line := range document.Lines {
+		if line.Rule != nil {
+			line.Rule.Owners = normalizeOwners(line.Rule.Owners)
+		} else {
+			line.Text = strings.TrimRight(line.Text, " \t")
+		}
+
+		blank := line.Rule == nil && line.Text == ""
+
+		// No blank lines at the start, and only one between anything else
+		if blank && (len(lines) == 0 || lines[len(lines)-1].Rule == nil && lines[len(lines)-1].Text == "") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(lines) > 0 && lines[len(lines)-1].Rule == nil && lines[len(lines)-1].Text == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	for start := 0; start < len(lines); start++ {
+		if lines[start].Rule == nil {
+			continue
+		}
+
+		end := start
+		for end < len(lines) && lines[end].Rule != nil {
+			end++
+		}
+
+		formatBlock(lines[start:end])
+		start = end
+	}
+
+	document.Lines = lines
+}
+
+// formatBlock sorts and aligns a run of rule lines
+func formatBlock(block []*DocumentLine) {
+	// A bubble sort that only swaps neighbours that can't match the same file, any other swap
+	// would change which rule wins for that file
+	for sorted := false; !sorted; {
+		sorted = true
+
+		for i := 1; i < len(block); i++ {
+			previous, current := block[i-1].Rule, block[i].Rule
+
+			if current.Pattern < previous.Pattern && patternsAreDisjoint(previous.Pattern, current.Pattern) {
+				block[i-1], block[i] = block[i], block[i-1]
+				sorted = false
+			}
+		}
+	}
+
+	width := 0
+	for _, line := range block {
+		width = max(width, len(line.Rule.Pattern)+1)
+	}
+
+	for _, line := range block {
+		line.Text = line.Rule.pad(width)
+	}
+}
+
+// normalizeOwners writes valid owners in lower case and removes repeats. Owners that aren't valid are
+// kept as they are, Lint reports them.
+func normalizeOwners(owners []string) []string {
+	normalized := []string{}
+
+	for _, owner := range owners {
+		if parsedOwner, err := ParseOwner(owner); err == nil {
+			owner = parsedOwner.Normalized()
+		}
+
+		if !slices.Contains(normalized, owner) {
+			normalized = append(normalized, owner)
+		}
+	}
+
+	return normalized
+}
+
+// patternsAreDisjoint reports whether no file can be matched by both patterns. Only patterns that
+// start with different literal directories are known to be disjoint, anything else might overlap.
+func patternsAreDisjoint(a string, b string) bool {
+	aPrefix, bPrefix := literalPrefix(a), literalPrefix(b)
+
+	for i := 0; i < len(aPrefix) && i < len(bPrefix); i++ {
+		if aPrefix[i] != bPrefix[i] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literalPrefix returns the segments at the start of the pattern that match a single path segment
+// exactly, which is none for patterns that can match at any depth
+func literalPrefix(pattern string) []string {
+	prefix := []string{}
+
+	for _, seg := range normalizeSegments(pattern) {
+		literal, isLiteral := literalSegment(seg)
+
+		if !isLiteral {
+			break
+		}
+
+		prefix = append(prefix, literal)
+	}
+
+	return prefix
+}
@@ -0,0 +1,83 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentFormat(t *testing.T) {
+	contents := "\n\n# Default owners\n*   @Org/Everyone\n\n\n/services/payments/  @org/payments @ORG/payments   # money\n/docs/ @org/docs\n/apps/ @org/apps @octocat\n*.md @org/writers\n/api/ @org/api\n\n/bad/***/   @org/bad  \n/config/\n\n"
+
+	document, err := ParseDocument(bytes.NewBufferString(contents), DialectGitHub)
+	assert.NoError(t, err)
+
+	document.Format()
+
+	// *.md can match anywhere, so nothing moves past it
+	assert.Equal(t, `# Default owners
+* @org/everyone
+
+/apps/              @org/apps @octocat
+/docs/              @org/docs
+/services/payments/ @org/payments # money
+*.md                @org/writers
+/api/               @org/api
+
+/bad/***/   @org/bad
+/config/
+`, string(document.Bytes()))
+}
+
+func TestDocumentFormat_keepsOwners(t *testing.T) {
+	contents := `/b/ @org/b
+/a/** @org/a
+/a/b/ @org/ab
+**/logs @org/ops
+/c/*.go @org/go
+/c/x/ @org/x
+/[abc]/ @org/brackets
+/\#hash/ @org/hash
+`
+
+	original, _ := FromReader(bytes.NewBufferString(contents))
+
+	document, err := ParseDocument(bytes.NewBufferString(contents), DialectGitHub)
+	assert.NoError(t, err)
+
+	document.Format()
+	formatted, err := document.Codeowners()
+	assert.NoError(t, err)
+
+	for _, file := range []string{"a/b/c.go", "a/x.go", "b/logs", "a/logs/1", "c/x/main.go", "c/main.go", "a/b", "[abc]/x", "#hash/x"} {
+		assert.Equal(t, original.FindOwners([]byte(file)), formatted.FindOwners([]byte(file)), file)
+	}
+
+	// Formatting twice changes nothing
+	once := document.Bytes()
+	document.Format()
+	assert.Equal(t, string(once), string(document.Bytes()))
+}
+
+func TestDocumentFormat_gitlab(t *testing.T) {
+	contents := "[Docs]   @org/docs  \n/docs/\n/api/docs/ @Org/API\n"
+
+	document, err := ParseDocument(bytes.NewBufferString(contents), DialectGitLab)
+	assert.NoError(t, err)
+
+	document.Format()
+
+	assert.Equal(t, "[Docs]   @org/docs\n/api/docs/ @org/api\n/docs/\n", string(document.Bytes()))
+}
+
+func TestPatternsAreDisjoint(t *testing.T) {
+	assert.True(t, patternsAreDisjoint("/docs/", "/api/"))
+	assert.True(t, patternsAreDisjoint("/a/b/", "/a/c/*.go"))
+	assert.True(t, patternsAreDisjoint("docs/api", "docs/web"))
+	assert.False(t, patternsAreDisjoint("/a/", "/a/b/"))
+	assert.False(t, patternsAreDisjoint("/a/*/c", "/a/b/"))
+	assert.False(t, patternsAreDisjoint("docs/", "/api/"))
+	assert.False(t, patternsAreDisjoint("*.md", "/api/"))
+	assert.False(t, patternsAreDisjoint("/**/a", "/b/"))
+}
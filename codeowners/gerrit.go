@@ -0,0 +1,470 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// OwnersFile is a Gerrit or Chromium style OWNERS file, which owns the directory it is in
+type OwnersFile struct {
+	// Path relative to the root of the repository
+	Path     string
+	Contents []byte
+}
+
+// perFileRule is every per-file line for the same glob in an OWNERS file
+type perFileRule struct {
+	glob   string
+	line   int
+	owners []string
+	// Only the per-file owners own the matching files, not the owners of the directory
+	noParent bool
+}
+
+// ownersInclude is an include or file: line, which adds the owners listed in another OWNERS file
+type ownersInclude struct {
+	path   string
+	line   int
+	column int
+}
+
+// parsedOwnersFile is the ownership declared by an OWNERS or .owners.yml file
+type parsedOwnersFile struct {
+	path string
+	dir  string
+	// Owners of the whole directory listed in the file itself
+	owners []string
+	// Owners of parent directories don't own this directory
+	noParent bool
+	includes []ownersInclude
+	perFile  []*perFileRule
+}
+
+// FromOwnersFiles builds the rules for a repository that uses an OWNERS file per directory. Every
+// directory with an OWNERS file becomes a rule for the directory owned by the owners in the file
+// along with the owners of its parent directories, unless the file says `set noparent`. A
+// `per-file glob=owners` line becomes a rule for the matching files in the directory. The rules are
+// ordered like a CODEOWNERS file so deeper directories win, and each rule's comment names the
+// OWNERS file it came from. Files named .owners.yml are read the same way, see IsOwnersFile.
+//
+// Like FromReader, malformed lines are skipped and reported through Diagnostics, which have the
+// Path of the OWNERS file set. An owner of `*`, which lets anyone approve, has no CODEOWNERS
+// equivalent and is skipped with a warning.
+func FromOwnersFiles(files []OwnersFile) (*Codeowners, error) {
+	rules, diagnostics := compileOwnersFiles(files)
+
+	defaultSection := &Section{Approvals: 1}
+	entries := make([]OwnerEntry, len(rules))
+
+	for i, rule := range rules {
+		entries[i] = *rule.entry
+		entries[i].Section = defaultSection
+	}
+
+	slices.Reverse(entries)
+
+	return &Codeowners{
+		entries:     entries,
+		diagnostics: diagnostics,
+		index:       newMatcher(entries),
+		lines:       len(entries),
+		dialect:     DialectGerrit,
+		sections:    []*Section{defaultSection},
+	}, nil
+}
+
+// GenerateCodeowners writes a CODEOWNERS file with the same rules as FromOwnersFiles builds from
+// the OWNERS files, after the given header. Rules are sorted by directory, parents first, and each
+// names the OWNERS file it came from. Returns the problems found in the OWNERS files, the rules
+// with problems are left out.
+func GenerateCodeowners(files []OwnersFile, header string) ([]byte, []Diagnostic) {
+	rules, diagnostics := compileOwnersFiles(files)
+
+	var generated bytes.Buffer
+	generated.WriteString(header)
+
+	if header != "" {
+		generated.WriteString("\n")
+	}
+
+	for _, rule := range rules {
+		generated.WriteString(rule.text)
+		generated.WriteString("\n")
+	}
+
+	return generated.Bytes(), diagnostics
+}
+
+// IsOwnersFile reports whether the repository relative path is a per-directory ownership file: a
+// Gerrit style OWNERS file or an .owners.yml file.
+func IsOwnersFile(filePath string) bool {
+	switch path.Base(filePath) {
+	case "OWNERS", ".owners.yml", ".owners.yaml":
+		return true
+	}
+
+	return false
+}
+
+// compileOwnersFiles turns the OWNERS files into CODEOWNERS rules, in the order they go in the file
+func compileOwnersFiles(files []OwnersFile) ([]compiledRule, []Diagnostic) {
+	diagnostics := []Diagnostic{}
+	byDir := map[string]*parsedOwnersFile{}
+	byPath := map[string]*parsedOwnersFile{}
+
+	for _, file := range files {
+		parse := parseOwnersFile
+		if path.Base(file.Path) != "OWNERS" {
+			parse = parseOwnersYAML
+		}
+
+		parsed, fileDiagnostics := parse(file)
+		diagnostics = append(diagnostics, fileDiagnostics...)
+
+		if existing, found := byDir[parsed.dir]; found {
+			// A directory with both kinds of file is owned by both
+			existing.merge(parsed)
+			parsed = existing
+		} else {
+			byDir[parsed.dir] = parsed
+		}
+
+		byPath[file.Path] = parsed
+	}
+
+	// Owners listed in a file, including the ones from the files it includes
+	var listedOwners func(file *parsedOwnersFile, visiting []*parsedOwnersFile) []string
+	listedOwners = func(file *parsedOwnersFile, visiting []*parsedOwnersFile) []string {
+		owners := slices.Clone(file.owners)
+		visiting = append(visiting, file)
+
+		for _, include := range file.includes {
+			included, found := byPath[include.path]
+
+			if !found {
+				// Missing includes are reported once, when the including file is compiled below
+				continue
+			}
+
+			if !slices.Contains(visiting, included) {
+				owners = appendOwners(owners, listedOwners(included, visiting)...)
+			}
+		}
+
+		return owners
+	}
+
+	// Owners of a directory, from its own OWNERS file and the OWNERS files above it
+	effective := map[string][]string{}
+	var directoryOwners func(dir string) []string
+	directoryOwners = func(dir string) []string {
+		if owners, found := effective[dir]; found {
+			return owners
+		}
+
+		owners := []string{}
+		file, found := byDir[dir]
+
+		if found {
+			owners = listedOwners(file, nil)
+		}
+
+		if dir != "." && (!found || !file.noParent) {
+			owners = appendOwners(slices.Clone(directoryOwners(path.Dir(dir))), owners...)
+		}
+
+		effective[dir] = owners
+		return owners
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+
+	// Parents sort before their children, so deeper directories win like later CODEOWNERS rules
+	slices.SortFunc(dirs, func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case a == ".":
+			return -1
+		case b == ".":
+			return 1
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	rules := []compiledRule{}
+
+	for _, dir := range dirs {
+		file := byDir[dir]
+
+		for _, include := range file.includes {
+			if _, found := byPath[include.path]; !found {
+				diagnostics = append(diagnostics, Diagnostic{
+					Path:     file.path,
+					Line:     include.line,
+					Column:   include.column,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("included file '%s' was not found, its owners are ignored", include.path),
+				})
+			}
+		}
+
+		dirPattern := "/" + EscapePattern(dir) + "/"
+		if dir == "." {
+			dirPattern = "*"
+		}
+
+		// A file without owners of its own doesn't change who owns the directory
+		if len(file.owners) > 0 || len(file.includes) > 0 || file.noParent {
+			rules = append(rules, newCompiledRule(dirPattern, directoryOwners(dir), file.path, 0))
+		}
+
+		for _, perFile := range file.perFile {
+			owners := perFile.owners
+			if !perFile.noParent {
+				owners = appendOwners(slices.Clone(directoryOwners(dir)), owners...)
+			}
+
+			pattern := dirPattern + perFile.glob
+			if dir == "." {
+				pattern = "/" + perFile.glob
+			}
+
+			rules = append(rules, newCompiledRule(pattern, owners, file.path, perFile.line))
+		}
+	}
+
+	compiled := []compiledRule{}
+
+	for _, rule := range rules {
+		entry, diagnostic := parseLine(rule.text, len(compiled)+1)
+
+		if diagnostic != nil {
+			// Only a per-file glob can be invalid, report it in the OWNERS file it came from
+			diagnostics = append(diagnostics, Diagnostic{
+				Path:     rule.path,
+				Line:     rule.line,
+				Column:   1,
+				Severity: SeverityError,
+				Message:  diagnostic.Message,
+			})
+			continue
+		}
+
+		rule.entry = entry
+		compiled = append(compiled, rule)
+	}
+
+	slices.SortStableFunc(diagnostics, compareDiagnostics)
+	return compiled, diagnostics
+}
+
+// merge adds the ownership declared by another file for the same directory
+func (file *parsedOwnersFile) merge(other *parsedOwnersFile) {
+	file.owners = appendOwners(file.owners, other.owners...)
+	file.noParent = file.noParent || other.noParent
+	file.includes = append(file.includes, other.includes...)
+	file.perFile = append(file.perFile, other.perFile...)
+}
+
+// perFileRule returns the rule for the glob, repeating a glob adds more owners to the same files
+func (file *parsedOwnersFile) perFileRule(glob string, lineNumber int) *perFileRule {
+	index := slices.IndexFunc(file.perFile, func(rule *perFileRule) bool {
+		return rule.glob == glob
+	})
+
+	if index == -1 {
+		file.perFile = append(file.perFile, &perFileRule{glob: glob, line: lineNumber})
+		index = len(file.perFile) - 1
+	}
+
+	return file.perFile[index]
+}
+
+// ownersFileReporter collects the problems found in a single OWNERS or .owners.yml file
+type ownersFileReporter struct {
+	path        string
+	diagnostics []Diagnostic
+}
+
+func (reporter *ownersFileReporter) report(severity Severity, lineNumber int, column int, format string, args ...any) {
+	reporter.diagnostics = append(reporter.diagnostics, Diagnostic{
+		Path:     reporter.path,
+		Line:     lineNumber,
+		Column:   column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// addOwner adds the owner to the list, unless it isn't an owner CODEOWNERS can use
+func (reporter *ownersFileReporter) addOwner(owners []string, owner string, lineNumber int, column int) []string {
+	if owner == "*" {
+		reporter.report(SeverityWarning, lineNumber, column, "'*' lets anyone approve, CODEOWNERS has no equivalent so it is ignored")
+		return owners
+	}
+
+	if _, err := ParseOwner(owner); err != nil {
+		reporter.report(SeverityError, lineNumber, column, "%v", err)
+		return owners
+	}
+
+	return appendOwners(owners, owner)
+}
+
+// parseOwnersFile parses the lines of a single OWNERS file
+func parseOwnersFile(file OwnersFile) (*parsedOwnersFile, []Diagnostic) {
+	parsed := &parsedOwnersFile{path: file.Path, dir: path.Dir(file.Path)}
+	reporter := &ownersFileReporter{path: file.Path}
+
+	lines := strings.Split(strings.TrimPrefix(string(file.Contents), "\ufeff"), "\n")
+
+	for i, line := range lines {
+		lineNumber := i + 1
+		line = strings.TrimSuffix(line, "\r")
+
+		if before, _, found := strings.Cut(line, "#"); found {
+			line = before
+		}
+
+		trimmed := strings.TrimSpace(line)
+		column := strings.Index(line, trimmed) + 1
+
+		if trimmed == "" {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+
+		switch {
+		case trimmed == "set noparent":
+			parsed.noParent = true
+		case fields[0] == "per-file":
+			globs, value, found := strings.Cut(strings.TrimSpace(strings.TrimPrefix(trimmed, "per-file")), "=")
+			value = strings.TrimSpace(value)
+
+			if !found || strings.TrimSpace(globs) == "" || value == "" {
+				reporter.report(SeverityError, lineNumber, column, "invalid per-file line '%s', expected per-file glob=owners", trimmed)
+				continue
+			}
+
+			if strings.HasPrefix(value, "file:") {
+				reporter.report(SeverityWarning, lineNumber, column, "per-file includes aren't supported, '%s' is ignored", trimmed)
+				continue
+			}
+
+			valueColumn := strings.LastIndex(line, value) + 1
+
+			for _, glob := range strings.Split(globs, ",") {
+				glob = strings.TrimSpace(glob)
+
+				if glob == "" {
+					reporter.report(SeverityError, lineNumber, column, "invalid per-file line '%s', expected per-file glob=owners", trimmed)
+					continue
+				}
+
+				// The glob becomes a CODEOWNERS pattern, so it has the same limits
+				if message := unsupportedSyntax(glob); message != "" {
+					reporter.report(SeverityError, lineNumber, column, "%s", message)
+				}
+
+				rule := parsed.perFileRule(glob, lineNumber)
+
+				if value == "set noparent" {
+					rule.noParent = true
+					continue
+				}
+
+				offset := 0
+				for _, owner := range strings.Split(value, ",") {
+					rule.owners = reporter.addOwner(rule.owners, strings.TrimSpace(owner), lineNumber, valueColumn+offset)
+					offset += len(owner) + 1
+				}
+			}
+		case fields[0] == "include" && len(fields) == 2:
+			parsed.includes = append(parsed.includes, ownersInclude{
+				path:   resolveOwnersInclude(parsed.dir, fields[1]),
+				line:   lineNumber,
+				column: column,
+			})
+		case strings.HasPrefix(trimmed, "file:") && len(fields) == 1:
+			parsed.includes = append(parsed.includes, ownersInclude{
+				path:   resolveOwnersInclude(parsed.dir, strings.TrimPrefix(trimmed, "file:")),
+				line:   lineNumber,
+				column: column,
+			})
+		case len(fields) == 1:
+			parsed.owners = reporter.addOwner(parsed.owners, trimmed, lineNumber, column)
+		default:
+			reporter.report(SeverityError, lineNumber, column, "unrecognized line '%s', expected an owner, per-file, include or set noparent", trimmed)
+		}
+	}
+
+	return parsed, reporter.diagnostics
+}
+
+// resolveOwnersInclude finds the OWNERS file an include refers to, paths starting with a slash, or two
+// like file://path, are relative to the root of the repository and the rest are relative to the including file
+func resolveOwnersInclude(dir string, includePath string) string {
+	if strings.HasPrefix(includePath, "/") {
+		return path.Clean(strings.TrimLeft(includePath, "/"))
+	}
+
+	return path.Join(dir, includePath)
+}
+
+// compiledRule is the CODEOWNERS rule for a directory or per-file line of an OWNERS file
+type compiledRule struct {
+	text string
+	path string
+	// Line of the per-file line, 0 for the rule for the whole directory
+	line  int
+	entry *OwnerEntry
+}
+
+// newCompiledRule writes a CODEOWNERS rule, with the OWNERS file it came from as its comment
+func newCompiledRule(pattern string, owners []string, filePath string, line int) compiledRule {
+	source := filePath
+	if line > 0 {
+		source = fmt.Sprintf("%s:%d", filePath, line)
+	}
+
+	return compiledRule{
+		text: strings.Join(append([]string{pattern}, owners...), " ") + " # " + source,
+		path: filePath,
+		line: line,
+	}
+}
+
+// appendOwners adds the owners that aren't already in the list, ignoring case
+func appendOwners(owners []string, more ...string) []string {
+	for _, owner := range more {
+		if !slices.ContainsFunc(owners, func(existing string) bool { return strings.EqualFold(existing, owner) }) {
+			owners = append(owners, owner)
+		}
+	}
+
+	return owners
+}
+
+// EscapePattern escapes a literal path so none of its characters are treated as pattern syntax
+func EscapePattern(literal string) string {
+	var escaped strings.Builder
+
+	for _, ch := range literal {
+		if strings.ContainsRune(" \t#\\*?[]!", ch) {
+			escaped.WriteByte('\\')
+		}
+
+		escaped.WriteRune(ch)
+	}
+
+	return escaped.String()
+}
@@ -0,0 +1,89 @@
+package codeowners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromOwnersFiles(t *testing.T) {
+	codeowners, err := FromOwnersFiles([]OwnersFile{
+		{Path: "OWNERS", Contents: []byte("# Everyone reviews the root\nroot@example.com\nper-file *.md=docs@example.com\n")},
+		{Path: "app/OWNERS", Contents: []byte("app@example.com\nper-file *.sql=dba@example.com, data@example.com\nper-file BUILD=set noparent\nper-file BUILD=build@example.com\n")},
+		{Path: "app/secure/OWNERS", Contents: []byte("set noparent\nsecurity@example.com\n")},
+		{Path: "app/secure/keys/OWNERS", Contents: []byte("keys@example.com # rotates the keys\n")},
+		{Path: "lib/OWNERS", Contents: []byte("file://app/OWNERS\n")},
+		{Path: "tools/OWNERS", Contents: []byte("per-file *.sh=ops@example.com\n")},
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, codeowners.Diagnostics())
+	assert.Equal(t, DialectGerrit, codeowners.Dialect())
+
+	assert.Equal(t, []string{"root@example.com"}, codeowners.FindOwners([]byte("main.go")))
+	assert.Equal(t, []string{"root@example.com"}, codeowners.FindOwners([]byte("docs/index.md")))
+	assert.Equal(t, []string{"root@example.com", "docs@example.com"}, codeowners.FindOwners([]byte("README.md")))
+
+	// Parent directories own their children
+	assert.Equal(t, []string{"root@example.com", "app@example.com"}, codeowners.FindOwners([]byte("app/main.go")))
+	assert.Equal(t, []string{"root@example.com", "app@example.com", "dba@example.com", "data@example.com"}, codeowners.FindOwners([]byte("app/schema.sql")))
+	assert.Equal(t, []string{"build@example.com"}, codeowners.FindOwners([]byte("app/BUILD")))
+
+	// Per-file lines only apply in their own directory
+	assert.Equal(t, []string{"root@example.com", "app@example.com"}, codeowners.FindOwners([]byte("app/models/schema.sql")))
+
+	// set noparent stops the owners of parent directories
+	assert.Equal(t, []string{"security@example.com"}, codeowners.FindOwners([]byte("app/secure/auth.go")))
+	assert.Equal(t, []string{"security@example.com", "keys@example.com"}, codeowners.FindOwners([]byte("app/secure/keys/id.pem")))
+
+	// Included files add their owners
+	assert.Equal(t, []string{"root@example.com", "app@example.com"}, codeowners.FindOwners([]byte("lib/util.go")))
+
+	// A file with only per-file lines leaves the directory to its parents
+	assert.Equal(t, []string{"root@example.com"}, codeowners.FindOwners([]byte("tools/build.go")))
+	assert.Equal(t, []string{"root@example.com", "ops@example.com"}, codeowners.FindOwners([]byte("tools/release.sh")))
+
+	// Rules say which OWNERS file they came from
+	assert.Equal(t, "/app/*.sql root@example.com app@example.com dba@example.com data@example.com # app/OWNERS:2", codeowners.Match([]byte("app/schema.sql")).String())
+	assert.Equal(t, "/app/secure/ security@example.com # app/secure/OWNERS", codeowners.Match([]byte("app/secure/auth.go")).String())
+
+	assert.Empty(t, Lint(codeowners, LintOptions{}))
+}
+
+func TestFromOwnersFiles_diagnostics(t *testing.T) {
+	codeowners, err := FromOwnersFiles([]OwnersFile{
+		{Path: "OWNERS", Contents: []byte("root@example.com\n*\nnot an owner\n@@team\n")},
+		{Path: "app/OWNERS", Contents: []byte("per-file *.go\nper-file [a-z].go=go@example.com\ninclude ../missing/OWNERS\n")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Path: "OWNERS", Line: 2, Column: 1, Severity: SeverityWarning, Message: "'*' lets anyone approve, CODEOWNERS has no equivalent so it is ignored"},
+		{Path: "OWNERS", Line: 3, Column: 1, Severity: SeverityError, Message: "unrecognized line 'not an owner', expected an owner, per-file, include or set noparent"},
+		{Path: "OWNERS", Line: 4, Column: 1, Severity: SeverityError, Message: "owner '@@team' is not a @user, @org/team or email address"},
+		{Path: "app/OWNERS", Line: 1, Column: 1, Severity: SeverityError, Message: "invalid per-file line 'per-file *.go', expected per-file glob=owners"},
+		{Path: "app/OWNERS", Line: 2, Column: 1, Severity: SeverityError, Message: "pattern '[a-z].go' uses a '[ ]' character range which GitHub does not support"},
+		{Path: "app/OWNERS", Line: 3, Column: 1, Severity: SeverityWarning, Message: "included file 'missing/OWNERS' was not found, its owners are ignored"},
+	}, codeowners.Diagnostics())
+
+	// The owners that could be read still own the files
+	assert.Equal(t, []string{"root@example.com"}, codeowners.FindOwners([]byte("app/main.go")))
+}
+
+func TestFromOwnersFiles_edit(t *testing.T) {
+	codeowners, err := FromOwnersFiles([]OwnersFile{
+		{Path: "OWNERS", Contents: []byte("root@example.com\n")},
+		{Path: "app/OWNERS", Contents: []byte("app@example.com\n")},
+	})
+
+	assert.NoError(t, err)
+
+	// Rules can be simulated on top of the rules built from the OWNERS files
+	proposed := codeowners.Clone()
+	added, err := proposed.InsertRule(0, "/app/api/ api@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, added.Line)
+	assert.Equal(t, []string{"api@example.com"}, proposed.FindOwners([]byte("app/api/main.go")))
+	assert.Equal(t, []string{"root@example.com", "app@example.com"}, codeowners.FindOwners([]byte("app/api/main.go")))
+}
@@ -0,0 +1,182 @@
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavor of CODEOWNERS syntax a file is written in
+type Dialect int
+
+const (
+	DialectGitHub Dialect = iota
+	// GitLab adds [Section] headers that each pick their own owners for a file
+	DialectGitLab
+	// Bitbucket adds @@@Group definitions that rules use as @@Group, and Check() merge checks
+	DialectBitbucket
+	// Gerrit and Chromium use an OWNERS file per directory instead of a CODEOWNERS file, see FromOwnersFiles
+	DialectGerrit
+)
+
+var dialectNames = []string{"github", "gitlab", "bitbucket", "gerrit"}
+
+func (dialect Dialect) String() string {
+	return dialectNames[dialect]
+}
+
+// ParseDialect parses the name of a dialect: github, gitlab, bitbucket or gerrit.
+func ParseDialect(name string) (Dialect, error) {
+	for dialect, dialectName := range dialectNames {
+		if strings.EqualFold(name, dialectName) {
+			return Dialect(dialect), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid dialect '%s', expected one of %s", name, strings.Join(dialectNames, ", "))
+}
+
+// Section is a group of rules that decides owners independently of the other sections. Only GitLab
+// has named sections, every GitHub rule is in the unnamed default section.
+type Section struct {
+	// Name as written in the first header for the section, empty for the default section
+	Name string
+	// Approval from an optional section's owners isn't required
+	Optional bool
+	// Number of approvals required from the section's owners
+	Approvals int
+	// Owners given in the first header for the section, used by rules that don't list any
+	DefaultOwners []string
+
+	// Position of the section in the file, the default section is always first
+	order int
+}
+
+// IsDefault reports whether this is the unnamed section for rules before any section header
+func (section *Section) IsDefault() bool {
+	return section.order == 0
+}
+
+// sectionHeader is a single [Section] line, a section is declared again by repeating its name
+type sectionHeader struct {
+	line          int
+	section       *Section
+	defaultOwners []string
+	parsedOwners  []Owner
+	ownerColumns  []int
+}
+
+// applyTo puts the rule in the header's section, giving it the header's default owners when it doesn't list any
+func (header *sectionHeader) applyTo(entry *OwnerEntry) {
+	entry.Section = header.section
+
+	if len(entry.Owners) == 0 && len(header.defaultOwners) > 0 {
+		entry.Owners = slices.Clone(header.defaultOwners)
+		entry.parsedOwners = header.parsedOwners
+		entry.DefaultOwners = true
+	}
+}
+
+// SectionMatch is the rule that decides a file's owners within one section
+type SectionMatch struct {
+	Section *Section
+	Entry   *OwnerEntry
+}
+
+// [Name], ^[Name] for optional sections and [Name][2] for the number of approvals, followed by default owners
+var sectionHeaderRE = regexp.MustCompile(`\A(\^)?\[([^\]]*)\](?:\[([^\]]*)\])?(.*)\z`)
+
+// parseSectionHeader parses a GitLab section header line. Returns false if the line isn't a header,
+// a pattern that starts with '[' has to be escaped as '\['.
+func parseSectionHeader(line string, lineNumber int) (*sectionHeader, *Diagnostic, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "^[") {
+		return nil, nil, false
+	}
+
+	column := len(line) - len(trimmed) + 1
+
+	invalid := func(format string, args ...any) (*sectionHeader, *Diagnostic, bool) {
+		return nil, &Diagnostic{
+			Line:     lineNumber,
+			Column:   column,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+		}, true
+	}
+
+	match := sectionHeaderRE.FindStringSubmatch(trimmed)
+
+	if match == nil {
+		return invalid("invalid section header '%s', expected [Section name]", trimmed)
+	}
+
+	name := strings.TrimSpace(match[2])
+
+	if name == "" {
+		return invalid("section header '%s' has no name", trimmed)
+	}
+
+	section := &Section{Name: name, Optional: match[1] != "", Approvals: 1}
+
+	if match[3] != "" {
+		approvals, err := strconv.Atoi(match[3])
+
+		if err != nil || approvals < 1 {
+			return invalid("invalid approval count '%s' in section header, expected a positive number", match[3])
+		}
+
+		section.Approvals = approvals
+	}
+
+	rest := match[4]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '#' {
+		return invalid("invalid section header '%s', expected owners after the section name", trimmed)
+	}
+
+	header := &sectionHeader{line: lineNumber, section: section}
+	restColumn := len(line) - len(rest)
+
+	tokens, _ := tokenizeLine(rest)
+	for _, ownerToken := range tokens {
+		header.defaultOwners = append(header.defaultOwners, ownerToken.text)
+		header.ownerColumns = append(header.ownerColumns, restColumn+ownerToken.column)
+
+		// Invalid owners are reported by Lint
+		if owner, err := ParseOwner(ownerToken.text); err == nil {
+			header.parsedOwners = append(header.parsedOwners, owner)
+		}
+	}
+
+	section.DefaultOwners = header.defaultOwners
+
+	return header, nil, true
+}
+
+// DetectDialect guesses the dialect of a CODEOWNERS file, files with GitLab section headers are GitLab,
+// files with Bitbucket group definitions or merge checks are Bitbucket and everything else is treated
+// as GitHub.
+func DetectDialect(contents []byte) Dialect {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	// Lines can be longer than the scanner's default limit
+	scanner.Buffer(nil, len(contents)+1)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if header, _, isHeader := parseSectionHeader(line, lineNumber); isHeader && header != nil {
+			return DialectGitLab
+		}
+
+		if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "@@@") || strings.HasPrefix(trimmed, "Check(") {
+			return DialectBitbucket
+		}
+	}
+
+	return DialectGitHub
+}
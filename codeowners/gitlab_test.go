@@ -0,0 +1,143 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gitlabCodeowners = `* @org/everyone
+
+[Documentation] @org/docs
+docs/
+*.md
+docs/api/ @org/api
+
+^[Translations][2] @org/l10n
+locales/
+
+[Backend][2]
+app/ @org/backend @octocat
+\[generated\]/ @org/tools
+
+[documentation]
+docs/internal/ @org/internal
+`
+
+func TestFromReaderDialect_gitlab(t *testing.T) {
+	codeowners, err := FromReaderDialect(bytes.NewBufferString(gitlabCodeowners), DialectGitLab)
+
+	assert.NoError(t, err)
+	assert.Empty(t, codeowners.Diagnostics())
+	assert.Equal(t, DialectGitLab, codeowners.Dialect())
+
+	type summary struct {
+		name          string
+		optional      bool
+		approvals     int
+		defaultOwners []string
+	}
+
+	sections := []summary{}
+	for _, section := range codeowners.Sections() {
+		sections = append(sections, summary{section.Name, section.Optional, section.Approvals, section.DefaultOwners})
+	}
+
+	// Repeated section names, ignoring case, add to the first section
+	assert.Equal(t, []summary{
+		{name: "", approvals: 1},
+		{name: "Documentation", approvals: 1, defaultOwners: []string{"@org/docs"}},
+		{name: "Translations", optional: true, approvals: 2, defaultOwners: []string{"@org/l10n"}},
+		{name: "Backend", approvals: 2},
+	}, sections)
+
+	// Every section picks its own owners
+	assert.Equal(t, []string{"@org/everyone", "@org/docs"}, codeowners.FindOwners([]byte("docs/index.md")))
+	assert.Equal(t, []string{"@org/everyone", "@org/api"}, codeowners.FindOwners([]byte("docs/api/README.md")))
+	assert.Equal(t, []string{"@org/everyone", "@org/internal"}, codeowners.FindOwners([]byte("docs/internal/design.md")))
+	assert.Equal(t, []string{"@org/everyone", "@org/backend", "@octocat"}, codeowners.FindOwners([]byte("app/main.go")))
+	assert.Equal(t, []string{"@org/everyone", "@org/tools"}, codeowners.FindOwners([]byte("[generated]/schema.go")))
+
+	matches := codeowners.MatchSections([]byte("locales/README.md"))
+	assert.Len(t, matches, 3)
+	assert.Equal(t, "", matches[0].Section.Name)
+	assert.Equal(t, "Documentation", matches[1].Section.Name)
+	assert.Equal(t, "*.md", matches[1].Entry.String())
+	assert.True(t, matches[1].Entry.DefaultOwners)
+	assert.Equal(t, "Translations", matches[2].Section.Name)
+	assert.Equal(t, []string{"@org/l10n"}, matches[2].Entry.Owners)
+
+	assert.True(t, codeowners.IsOwnedBy([]byte("locales/fr.json"), "@Org/L10n"))
+	assert.Equal(t, 1, codeowners.Match([]byte("locales/fr.json")).Line)
+
+	// Inserted rules join the section they're inserted into
+	proposed := codeowners.Clone()
+	entry, err := proposed.InsertRule(7, "guides/")
+	assert.NoError(t, err)
+	assert.Equal(t, "Documentation", entry.Section.Name)
+	assert.Equal(t, []string{"@org/everyone", "@org/docs"}, proposed.FindOwners([]byte("guides/setup.txt")))
+	assert.Equal(t, "Translations", proposed.MatchSections([]byte("locales/fr.json"))[1].Section.Name)
+}
+
+func TestFromReaderDialect_gitlabDiagnostics(t *testing.T) {
+	codeowners, err := FromReaderDialect(bytes.NewBufferString(`[Docs
+[] @org/docs
+[Docs][zero] @org/docs
+[Docs]@org/docs
+[Backend] not-an-owner
+app/
+`), DialectGitLab)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Line: 1, Column: 1, Message: "invalid section header '[Docs', expected [Section name]"},
+		{Line: 2, Column: 1, Message: "section header '[] @org/docs' has no name"},
+		{Line: 3, Column: 1, Message: "invalid approval count 'zero' in section header, expected a positive number"},
+		{Line: 4, Column: 1, Message: "invalid section header '[Docs]@org/docs', expected owners after the section name"},
+	}, codeowners.Diagnostics())
+
+	// Invalid default owners are reported against the header, not every rule using them
+	assert.Equal(t, []Diagnostic{
+		{Line: 1, Column: 1, Message: "invalid section header '[Docs', expected [Section name]"},
+		{Line: 2, Column: 1, Message: "section header '[] @org/docs' has no name"},
+		{Line: 3, Column: 1, Message: "invalid approval count 'zero' in section header, expected a positive number"},
+		{Line: 4, Column: 1, Message: "invalid section header '[Docs]@org/docs', expected owners after the section name"},
+		{Line: 5, Column: 11, Message: "owner 'not-an-owner' is not a @user, @org/team or email address"},
+	}, Lint(codeowners, LintOptions{}))
+}
+
+func TestUsage_gitlabSections(t *testing.T) {
+	codeowners, err := FromReaderDialect(bytes.NewBufferString(`[Docs]
+docs/ @org/docs
+
+[Review]
+* @org/reviewers
+docs/ @org/docs
+`), DialectGitLab)
+
+	assert.NoError(t, err)
+
+	usages := codeowners.Usage([]string{"docs/index.md"})
+
+	// The same pattern in another section isn't a duplicate and doesn't shadow anything
+	assert.Equal(t, 1, usages[0].Won)
+	assert.Empty(t, usages[0].ShadowedBy)
+	assert.Equal(t, []int{6}, usages[1].ShadowedBy)
+	assert.Equal(t, 1, usages[2].Won)
+
+	assert.Empty(t, Lint(codeowners, LintOptions{}))
+}
+
+func TestDetectDialect(t *testing.T) {
+	assert.Equal(t, DialectGitLab, DetectDialect([]byte(gitlabCodeowners)))
+	assert.Equal(t, DialectGitHub, DetectDialect([]byte("* @org/everyone\ndocs/ @org/docs\n")))
+	assert.Equal(t, DialectGitHub, DetectDialect([]byte("\\[generated\\]/ @org/tools\n")))
+
+	dialect, err := ParseDialect("GitLab")
+	assert.NoError(t, err)
+	assert.Equal(t, DialectGitLab, dialect)
+
+	_, err = ParseDialect("azure")
+	assert.EqualError(t, err, "invalid dialect 'azure', expected one of github, gitlab, bitbucket, gerrit")
+}
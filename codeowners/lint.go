@@ -0,0 +1,141 @@
+package codeowners
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MaxFileSize is the largest CODEOWNERS file GitHub will read, larger files are ignored entirely
+const MaxFileSize = 3 * 1024 * 1024
+
+type LintOptions struct {
+	// Repository relative path of the CODEOWNERS file, used to check that it owns itself
+	Path string
+	// Size of the CODEOWNERS file in bytes
+	Size int
+}
+
+// Lint checks the parsed CODEOWNERS file for problems beyond what stops a line from parsing:
+// syntax GitHub doesn't support, malformed owners, duplicate patterns, the file size limit and
+// whether the file owns itself. Parse diagnostics are included, everything is sorted by position.
+func Lint(co *Codeowners, opts LintOptions) []Diagnostic {
+	diagnostics := slices.Clone(co.Diagnostics())
+
+	if opts.Size > MaxFileSize {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("file is %d bytes, GitHub ignores CODEOWNERS files larger than %d bytes", opts.Size, MaxFileSize),
+		})
+	}
+
+	// Patterns are only duplicates within a section, every section decides owners separately
+	type sectionPattern struct {
+		section *Section
+		pattern string
+	}
+
+	firstDeclared := map[sectionPattern]int{}
+
+	// Entries are stored in match order, walk them backwards to report in file order. Rules built
+	// from OWNERS files were checked as the files were read, in terms of their own lines.
+	for i := len(co.entries) - 1; i >= 0 && co.dialect != DialectGerrit; i-- {
+		entry := &co.entries[i]
+
+		if message := unsupportedSyntax(entry.Pattern); message != "" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     entry.Line,
+				Column:   entry.patternColumn,
+				Severity: SeverityError,
+				Message:  message,
+			})
+		}
+
+		key := sectionPattern{section: entry.Section, pattern: entry.Pattern}
+
+		if line, found := firstDeclared[key]; found {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     entry.Line,
+				Column:   entry.patternColumn,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("duplicate pattern '%s', the rule on line %d has no effect", entry.Pattern, line),
+			})
+		}
+
+		firstDeclared[key] = entry.Line
+
+		// Default owners come from the section header, they are checked there
+		if !entry.DefaultOwners {
+			diagnostics = append(diagnostics, lintOwners(co.dialect, entry.Line, entry.Owners, entry.ownerColumns)...)
+		}
+	}
+
+	for _, header := range co.headers {
+		diagnostics = append(diagnostics, lintOwners(co.dialect, header.line, header.defaultOwners, header.ownerColumns)...)
+	}
+
+	if opts.Path != "" && len(co.FindOwners([]byte(opts.Path))) == 0 {
+		message := fmt.Sprintf("'%s' is not owned by any rule, changes to it won't require a review", opts.Path)
+
+		// A rule without owners still leaves the file without a review
+		if entry := co.Match([]byte(opts.Path)); entry != nil {
+			message = fmt.Sprintf("'%s' is left without owners by line %d, changes to it won't require a review", opts.Path, entry.Line)
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Message:  message,
+		})
+	}
+
+	slices.SortStableFunc(diagnostics, compareDiagnostics)
+
+	return diagnostics
+}
+
+// lintOwners reports the owners that aren't a @user, @org/team or email address. Bitbucket groups
+// left on a rule are undefined, which parsing already reported.
+func lintOwners(dialect Dialect, line int, owners []string, columns []int) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for i, owner := range owners {
+		if dialect == DialectBitbucket && isGroupRef(owner) {
+			continue
+		}
+
+		if _, err := ParseOwner(owner); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     line,
+				Column:   columns[i],
+				Severity: SeverityError,
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// unsupportedSyntax returns a message describing gitignore syntax that GitHub doesn't honor in
+// CODEOWNERS patterns, or an empty string if the pattern only uses supported syntax.
+func unsupportedSyntax(pattern string) string {
+	if pattern[0] == '!' {
+		return fmt.Sprintf("pattern '%s' uses '!' negation which GitHub does not support", pattern)
+	}
+
+	escape := false
+	for _, ch := range pattern {
+		if escape {
+			escape = false
+			continue
+		}
+
+		switch ch {
+		case '\\':
+			escape = true
+		case '[', ']':
+			return fmt.Sprintf("pattern '%s' uses a '[ ]' character range which GitHub does not support", pattern)
+		}
+	}
+
+	return ""
+}
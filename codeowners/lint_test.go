@@ -0,0 +1,55 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`* @org/everyone
+!docs/ @org/docs
+src/[a-z]*.go @user
+src/*** @user
+lib/ not-an-owner @user
+lib/ @org/lib
+`))
+
+	assert.NoError(t, err)
+
+	diagnostics := Lint(codeowners, LintOptions{Path: ".github/CODEOWNERS", Size: MaxFileSize + 1})
+
+	assert.Equal(t, []Diagnostic{
+		{Line: 0, Column: 0, Severity: SeverityError, Message: "file is 3145729 bytes, GitHub ignores CODEOWNERS files larger than 3145728 bytes"},
+		{Line: 2, Column: 1, Severity: SeverityError, Message: "pattern '!docs/' uses '!' negation which GitHub does not support"},
+		{Line: 3, Column: 1, Severity: SeverityError, Message: "pattern 'src/[a-z]*.go' uses a '[ ]' character range which GitHub does not support"},
+		{Line: 4, Column: 1, Severity: SeverityError, Message: "invalid pattern 'src/***': pattern cannot contain three consecutive asterisks"},
+		{Line: 5, Column: 6, Severity: SeverityError, Message: "owner 'not-an-owner' is not a @user, @org/team or email address"},
+		{Line: 6, Column: 1, Severity: SeverityWarning, Message: "duplicate pattern 'lib/', the rule on line 5 has no effect"},
+	}, diagnostics)
+}
+
+func TestLint_notOwningItself(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString("src/ @org/team docs@example.com\n"))
+
+	assert.NoError(t, err)
+
+	diagnostics := Lint(codeowners, LintOptions{Path: ".github/CODEOWNERS", Size: 10})
+
+	assert.Equal(t, []Diagnostic{
+		{Severity: SeverityWarning, Message: "'.github/CODEOWNERS' is not owned by any rule, changes to it won't require a review"},
+	}, diagnostics)
+}
+
+func TestLint_notOwningItself_ownerlessRule(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString("* @org/team\n/.github/\n"))
+
+	assert.NoError(t, err)
+
+	diagnostics := Lint(codeowners, LintOptions{Path: ".github/CODEOWNERS", Size: 10})
+
+	assert.Equal(t, []Diagnostic{
+		{Severity: SeverityWarning, Message: "'.github/CODEOWNERS' is left without owners by line 2, changes to it won't require a review"},
+	}, diagnostics)
+}
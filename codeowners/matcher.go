@@ -0,0 +1,267 @@
+package codeowners
+
+import (
+	"slices"
+	"strings"
+)
+
+// matcher indexes the rules of a CODEOWNERS file so a path is only checked against rules that
+// could possibly match it. Rules made of literal path segments are matched by walking a trie
+// of those segments without running a regex at all, rules that only match a single literal
+// name anywhere in the tree are looked up by that name and wildcard rules are filed under the
+// literal directories they start with. Only those remaining candidates fall back to the regex
+// from buildPatternRegex, so results are always the same as checking every rule in order.
+type matcher struct {
+	entries []OwnerEntry
+	root    *trieNode
+	// Unanchored rules matching a literal name at any depth, e.g. "build" or "build/"
+	byName map[string][]nameRule
+	// Unanchored rules matching any name at any depth, e.g. "*"
+	anyName []nameRule
+	// Unanchored rules matching a name suffix at any depth keyed by extension, e.g. "*.go"
+	byExtension map[string][]int
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	// Rules matching the path of this node and everything under it, e.g. "/docs"
+	exact []int
+	// Rules matching only what is under the path of this node, e.g. "/docs/"
+	descendants []int
+	// Rules that start with the path of this node and need their regex checked, e.g. "/docs/*.md"
+	candidates []int
+}
+
+type nameRule struct {
+	index int
+	// Only match when the name is a directory, e.g. "build/"
+	dirOnly bool
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: map[string]*trieNode{}}
+}
+
+// newMatcher indexes the given entries, which must be in match order with the winning rule first
+func newMatcher(entries []OwnerEntry) *matcher {
+	m := &matcher{
+		entries:     entries,
+		root:        newTrieNode(),
+		byName:      map[string][]nameRule{},
+		byExtension: map[string][]int{},
+	}
+
+	for i := range entries {
+		m.add(i, entries[i].Pattern)
+	}
+
+	return m
+}
+
+func (m *matcher) add(index int, pattern string) {
+	// "/" is special cased by buildPatternRegex, let the regex deal with it
+	if pattern == "/" {
+		m.root.candidates = append(m.root.candidates, index)
+		return
+	}
+
+	segs := normalizeSegments(pattern)
+
+	if segs[0] == "**" && len(segs) > 1 {
+		name := segs[1]
+		dirOnly := len(segs) == 3 && segs[2] == "**"
+
+		if len(segs) == 2 || dirOnly {
+			if name == "*" {
+				m.anyName = append(m.anyName, nameRule{index: index, dirOnly: dirOnly})
+				return
+			}
+
+			if literal, ok := literalSegment(name); ok && literal != "" {
+				m.byName[literal] = append(m.byName[literal], nameRule{index: index, dirOnly: dirOnly})
+				return
+			}
+
+			if suffix, ok := literalSegment(strings.TrimPrefix(name, "*")); ok && strings.HasPrefix(name, "*") && strings.Contains(suffix, ".") {
+				extension := suffix[strings.LastIndex(suffix, ".")+1:]
+				m.byExtension[extension] = append(m.byExtension[extension], index)
+				return
+			}
+		}
+
+		m.root.candidates = append(m.root.candidates, index)
+		return
+	}
+
+	node := m.root
+	depth := 0
+	for _, seg := range segs {
+		literal, ok := literalSegment(seg)
+
+		if !ok {
+			break
+		}
+
+		child, found := node.children[literal]
+		if !found {
+			child = newTrieNode()
+			node.children[literal] = child
+		}
+
+		node = child
+		depth++
+	}
+
+	switch {
+	case depth == len(segs):
+		node.exact = append(node.exact, index)
+	case depth > 0 && depth == len(segs)-1 && segs[depth] == "**":
+		node.descendants = append(node.descendants, index)
+	default:
+		node.candidates = append(node.candidates, index)
+	}
+}
+
+// match returns the indexes of the matching entries in match order. When all is false only the
+// winning entry is returned.
+func (m *matcher) match(fileName []byte, all bool) []int {
+	path := string(fileName)
+	pathSegs := strings.Split(path, "/")
+
+	matched := []int{}
+	candidates := []int{}
+
+	node := m.root
+	candidates = append(candidates, node.candidates...)
+	for depth, seg := range pathSegs {
+		child, found := node.children[seg]
+
+		if !found {
+			break
+		}
+
+		node = child
+		matched = append(matched, node.exact...)
+
+		if len(pathSegs) > depth+1 {
+			matched = append(matched, node.descendants...)
+		}
+
+		candidates = append(candidates, node.candidates...)
+	}
+
+	for i, seg := range pathSegs {
+		// The regex requires a non-empty leading path before a name that isn't at the start
+		if i == 1 && pathSegs[0] == "" {
+			continue
+		}
+
+		for _, rule := range m.byName[seg] {
+			if !rule.dirOnly || i < len(pathSegs)-1 {
+				matched = append(matched, rule.index)
+			}
+		}
+
+		if seg != "" {
+			for _, rule := range m.anyName {
+				// Unlike a named segment a lone "*" doesn't match descendants, it has to be the last segment
+				if rule.dirOnly == (i < len(pathSegs)-1) {
+					matched = append(matched, rule.index)
+				}
+			}
+		}
+
+		if dot := strings.LastIndexByte(seg, '.'); dot != -1 {
+			candidates = append(candidates, m.byExtension[seg[dot+1:]]...)
+		}
+	}
+
+	slices.Sort(matched)
+
+	if !all && len(matched) > 0 {
+		// Only candidates that would beat the best match are worth a regex
+		matched = matched[:1]
+		candidates = slices.DeleteFunc(candidates, func(index int) bool {
+			return index > matched[0]
+		})
+	}
+
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	for _, index := range candidates {
+		if m.entries[index].matcher.MatchString(path) {
+			matched = append(matched, index)
+
+			if !all {
+				break
+			}
+		}
+	}
+
+	slices.Sort(matched)
+	matched = slices.Compact(matched)
+
+	if !all && len(matched) > 1 {
+		matched = matched[:1]
+	}
+
+	return matched
+}
+
+// normalizeSegments splits a pattern into the path segments buildPatternRegex matches. A leading slash
+// anchors the pattern to the root, as does a slash anywhere but the end. Other patterns match at any
+// depth and get a leading "**", and a trailing slash becomes a trailing "**".
+func normalizeSegments(pattern string) []string {
+	segs := strings.Split(pattern, "/")
+
+	if segs[0] == "" {
+		// Leading slash: match is relative to root
+		segs = segs[1:]
+	} else if len(segs) == 1 || (len(segs) == 2 && segs[1] == "") {
+		// A single segment pattern matches relative to any descendent path
+		if segs[0] != "**" {
+			segs = append([]string{"**"}, segs...)
+		}
+	}
+
+	// "**/**" matches the same paths as "**", and the regex for "**" next to "**" would need an empty segment
+	segs = slices.CompactFunc(segs, func(a string, b string) bool { return a == "**" && b == "**" })
+
+	if len(segs) == 2 && segs[0] == "**" && segs[1] == "" {
+		// "**/" is every directory, like "*/"
+		segs = []string{"**", "*", ""}
+	}
+
+	if len(segs) > 1 && segs[len(segs)-1] == "" {
+		// Trailing slash is equivalent to "/**"
+		segs[len(segs)-1] = "**"
+	}
+
+	return segs
+}
+
+// literalSegment returns the unescaped text of a pattern segment if it contains no wildcards
+func literalSegment(seg string) (string, bool) {
+	var literal strings.Builder
+
+	escape := false
+	for _, ch := range seg {
+		if escape {
+			escape = false
+			literal.WriteRune(ch)
+			continue
+		}
+
+		switch ch {
+		case '\\':
+			escape = true
+		case '*', '?':
+			return "", false
+		default:
+			literal.WriteRune(ch)
+		}
+	}
+
+	return literal.String(), true
+}
@@ -0,0 +1,126 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// matchLinear checks every rule's regex in order, which is what the matcher has to agree with
+func matchLinear(co *Codeowners, fileName []byte) []int {
+	matched := []int{}
+
+	for i := range co.entries {
+		if co.entries[i].matcher.Match(fileName) {
+			matched = append(matched, i)
+		}
+	}
+
+	return matched
+}
+
+func TestMatcher_agreesWithRegex(t *testing.T) {
+	patterns := []string{
+		"*", "**", "/", "/**", "**/", "*/", "/*", "docs", "docs/", "/docs", "/docs/", "docs/**", "/docs/**",
+		"docs/*", "docs/*.md", "*.md", "*.test.js", "**/docs", "**/docs/", "**/docs/*.md", "docs/**/*.md",
+		"src/docs", "src/**/docs/", "a//b", "src/main.go", "my\\ docs/", "\\#notes", "d?cs/", "*cs",
+		"src/*/internal/", "/src/app/", "app", "app/", "**/app/**", "/**/app", "*.go/", ".github/",
+		"**/", "/**/", "**/**", "src/**/**/app", "docs/**/",
+	}
+
+	paths := []string{
+		"", "docs", "docs/readme.md", "docs/nested/readme.md", "src/docs/readme.md", "src/docs",
+		"src/main.go", "src/app/main.go", "src/app", "app/main.go", "app", "a//b", "a/b", "/docs/readme.md",
+		"my docs/readme.md", "#notes", "dacs/file", "src/pkg/internal/file.go", "src/pkg/internal",
+		"test.js", "lib/util.test.js", "main.go/file", ".github/CODEOWNERS", "docs/", "new\nline/docs/file",
+	}
+
+	random := rand.New(rand.NewSource(1))
+
+	for run := 0; run < 200; run++ {
+		var contents strings.Builder
+		for i := 0; i < 1+random.Intn(8); i++ {
+			fmt.Fprintf(&contents, "%s @team-%d\n", patterns[random.Intn(len(patterns))], i)
+		}
+
+		codeowners, err := FromReader(bytes.NewBufferString(contents.String()))
+		assert.NoError(t, err)
+
+		for _, path := range paths {
+			expected := matchLinear(codeowners, []byte(path))
+			assert.Equal(t, expected, codeowners.index.match([]byte(path), true), "all matches for '%s' with:\n%s", path, contents.String())
+
+			if len(expected) > 0 {
+				expected = expected[:1]
+			}
+			assert.Equal(t, expected, codeowners.index.match([]byte(path), false), "winning match for '%s' with:\n%s", path, contents.String())
+		}
+	}
+}
+
+// buildLargeCodeowners makes a monorepo sized CODEOWNERS file along with the paths of its files
+func buildLargeCodeowners(b *testing.B, rules int, files int) (*Codeowners, [][]byte) {
+	random := rand.New(rand.NewSource(1))
+
+	var contents strings.Builder
+	contents.WriteString("* @org/everyone\n*.md @org/docs\n")
+
+	for i := 0; i < rules; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&contents, "/services/service-%d/ @org/team-%d\n", i, i)
+		case 1:
+			fmt.Fprintf(&contents, "/libs/lib-%d/src/ @org/team-%d\n", i, i)
+		case 2:
+			fmt.Fprintf(&contents, "/services/service-%d/*.proto @org/api-%d\n", i-2, i)
+		default:
+			fmt.Fprintf(&contents, "generated-%d/ @org/bots\n", i)
+		}
+	}
+
+	codeowners, err := FromReader(bytes.NewBufferString(contents.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	paths := make([][]byte, files)
+	for i := range paths {
+		n := random.Intn(rules)
+		switch i % 3 {
+		case 0:
+			paths[i] = fmt.Appendf(nil, "services/service-%d/pkg/handler_%d.go", n, i)
+		case 1:
+			paths[i] = fmt.Appendf(nil, "libs/lib-%d/src/internal/file_%d.proto", n, i)
+		default:
+			paths[i] = fmt.Appendf(nil, "tools/generated-%d/docs/README_%d.md", n, i)
+		}
+	}
+
+	return codeowners, paths
+}
+
+func BenchmarkMatch(b *testing.B) {
+	codeowners, paths := buildLargeCodeowners(b, 4000, 50_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		codeowners.Match(paths[i%len(paths)])
+	}
+}
+
+func BenchmarkMatch_linear(b *testing.B) {
+	codeowners, paths := buildLargeCodeowners(b, 4000, 50_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, entry := range codeowners.entries {
+			if entry.matcher.Match(paths[i%len(paths)]) {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package codeowners
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OwnerKind is the kind of account an owner refers to
+type OwnerKind int
+
+const (
+	OwnerUser OwnerKind = iota
+	OwnerTeam
+	OwnerEmail
+)
+
+var ownerKindNames = []string{"user", "team", "email"}
+
+func (kind OwnerKind) String() string {
+	return ownerKindNames[kind]
+}
+
+// ParseOwnerKind parses the name of an owner kind: user, team or email.
+func ParseOwnerKind(name string) (OwnerKind, error) {
+	for kind, kindName := range ownerKindNames {
+		if strings.EqualFold(name, kindName) {
+			return OwnerKind(kind), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid owner kind '%s', expected one of %s", name, strings.Join(ownerKindNames, ", "))
+}
+
+var (
+	userOwnerRE  = regexp.MustCompile(`\A@([A-Za-z0-9](?:-?[A-Za-z0-9])*)\z`)
+	teamOwnerRE  = regexp.MustCompile(`\A@([A-Za-z0-9](?:-?[A-Za-z0-9])*)/([A-Za-z0-9._-]+)\z`)
+	emailOwnerRE = regexp.MustCompile(`\A[^@\s]+@[^@\s]+\.[^@\s]+\z`)
+)
+
+// Owner is a single owner of a rule: a @user, an @org/team or an email address
+type Owner struct {
+	Kind OwnerKind
+	// Organization a team belongs to, empty for users and emails
+	Org string
+	// Login of a user, name of a team without the organization or the address of an email
+	Slug string
+
+	// The owner as it was written
+	text string
+}
+
+// ParseOwner parses an owner as it is written in a CODEOWNERS file.
+func ParseOwner(text string) (Owner, error) {
+	if match := userOwnerRE.FindStringSubmatch(text); match != nil {
+		return Owner{Kind: OwnerUser, Slug: match[1], text: text}, nil
+	}
+
+	if match := teamOwnerRE.FindStringSubmatch(text); match != nil {
+		return Owner{Kind: OwnerTeam, Org: match[1], Slug: match[2], text: text}, nil
+	}
+
+	if emailOwnerRE.MatchString(text) {
+		return Owner{Kind: OwnerEmail, Slug: text, text: text}, nil
+	}
+
+	return Owner{}, fmt.Errorf("owner '%s' is not a @user, @org/team or email address", text)
+}
+
+// String returns the owner as it was written
+func (owner Owner) String() string {
+	return owner.text
+}
+
+// Normalized returns the owner in lower case, GitHub logins, team names and emails are all case insensitive.
+// Two owners are the same owner when their normalized forms are equal.
+func (owner Owner) Normalized() string {
+	return strings.ToLower(owner.text)
+}
+
+// Equal reports whether both refer to the same owner, ignoring case
+func (owner Owner) Equal(other Owner) bool {
+	return owner.Kind == other.Kind && strings.EqualFold(owner.Org, other.Org) && strings.EqualFold(owner.Slug, other.Slug)
+}
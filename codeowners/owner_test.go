@@ -0,0 +1,78 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOwner(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Owner
+		err      string
+	}{
+		{text: "@octocat", expected: Owner{Kind: OwnerUser, Slug: "octocat", text: "@octocat"}},
+		{text: "@My-Org/Web.Team_1", expected: Owner{Kind: OwnerTeam, Org: "My-Org", Slug: "Web.Team_1", text: "@My-Org/Web.Team_1"}},
+		{text: "docs@example.com", expected: Owner{Kind: OwnerEmail, Slug: "docs@example.com", text: "docs@example.com"}},
+		{text: "octocat", err: "owner 'octocat' is not a @user, @org/team or email address"},
+		{text: "@-octocat", err: "owner '@-octocat' is not a @user, @org/team or email address"},
+		{text: "@org/team/nested", err: "owner '@org/team/nested' is not a @user, @org/team or email address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			owner, err := ParseOwner(tt.text)
+
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, owner)
+			assert.Equal(t, tt.text, owner.String())
+		})
+	}
+}
+
+func TestOwnerEqual(t *testing.T) {
+	parse := func(text string) Owner {
+		owner, err := ParseOwner(text)
+		assert.NoError(t, err)
+		return owner
+	}
+
+	assert.True(t, parse("@Org/Team").Equal(parse("@org/team")))
+	assert.True(t, parse("@OctoCat").Equal(parse("@octocat")))
+	assert.True(t, parse("Docs@Example.com").Equal(parse("docs@example.com")))
+	assert.Equal(t, "@org/team", parse("@Org/Team").Normalized())
+
+	assert.False(t, parse("@org/team").Equal(parse("@other/team")))
+	assert.False(t, parse("@org").Equal(parse("@org/org")))
+}
+
+func TestParseOwnerKind(t *testing.T) {
+	kind, err := ParseOwnerKind("Team")
+	assert.NoError(t, err)
+	assert.Equal(t, OwnerTeam, kind)
+	assert.Equal(t, "team", kind.String())
+
+	_, err = ParseOwnerKind("bot")
+	assert.EqualError(t, err, "invalid owner kind 'bot', expected one of user, team, email")
+}
+
+func TestIsOwnedBy_ignoresCase(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString("src/ @org/team @octocat not-an-owner\n"))
+	assert.NoError(t, err)
+
+	assert.True(t, codeowners.IsOwnedBy([]byte("src/main.go"), "@Org/Team"))
+	assert.True(t, codeowners.IsOwnedBy([]byte("src/main.go"), "@OCTOCAT"))
+	assert.True(t, codeowners.IsOwnedBy([]byte("src/main.go"), "not-an-owner"))
+	assert.False(t, codeowners.IsOwnedBy([]byte("src/main.go"), "@org/other"))
+	assert.False(t, codeowners.IsOwnedBy([]byte("README.md"), "@org/team"))
+
+	entry := codeowners.Match([]byte("src/main.go"))
+	assert.Len(t, entry.ParsedOwners(), 2)
+}
@@ -0,0 +1,129 @@
+package codeowners
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ownersYAML is an .owners.yml file, the YAML form of an OWNERS file:
+//
+//	owners: ["@org/backend"]
+//	noparent: true
+//	include: [/shared/OWNERS]
+//	files:
+//	  - pattern: "*.sql"
+//	    owners: ["@org/dba"]
+//
+// Nodes are kept for their line and column, so problems point at the owner that has them.
+type ownersYAML struct {
+	Owners   []yaml.Node `yaml:"owners"`
+	NoParent bool        `yaml:"noparent"`
+	Include  []yaml.Node `yaml:"include"`
+	Files    []struct {
+		Pattern  yaml.Node   `yaml:"pattern"`
+		Owners   []yaml.Node `yaml:"owners"`
+		NoParent bool        `yaml:"noparent"`
+	} `yaml:"files"`
+}
+
+// parseOwnersYAML parses an .owners.yml file, which declares the same things as an OWNERS file
+func parseOwnersYAML(file OwnersFile) (*parsedOwnersFile, []Diagnostic) {
+	parsed := &parsedOwnersFile{path: file.Path, dir: path.Dir(file.Path)}
+	reporter := &ownersFileReporter{path: file.Path}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(file.Contents))
+	// Misspelled keys would otherwise silently drop owners
+	decoder.KnownFields(true)
+
+	var declared ownersYAML
+	if err := decoder.Decode(&declared); err != nil && !errors.Is(err, io.EOF) {
+		var typeError *yaml.TypeError
+
+		if !errors.As(err, &typeError) {
+			reportYAMLError(reporter, strings.TrimPrefix(err.Error(), "yaml: "))
+			return parsed, reporter.diagnostics
+		}
+
+		// The rest of the file is still decoded, only the values with the wrong type are left out
+		for _, message := range typeError.Errors {
+			reportYAMLError(reporter, message)
+		}
+	}
+
+	for _, owner := range declared.Owners {
+		parsed.owners = reporter.addOwner(parsed.owners, owner.Value, owner.Line, owner.Column)
+	}
+
+	parsed.noParent = declared.NoParent
+
+	for _, include := range declared.Include {
+		parsed.includes = append(parsed.includes, ownersInclude{
+			path:   resolveOwnersInclude(parsed.dir, include.Value),
+			line:   include.Line,
+			column: include.Column,
+		})
+	}
+
+	for _, files := range declared.Files {
+		glob := strings.TrimSpace(files.Pattern.Value)
+
+		if glob == "" {
+			reporter.report(SeverityError, files.Pattern.Line, files.Pattern.Column, "files entry has no pattern")
+			continue
+		}
+
+		// The pattern becomes a CODEOWNERS pattern, so it has the same limits
+		if message := unsupportedSyntax(glob); message != "" {
+			reporter.report(SeverityError, files.Pattern.Line, files.Pattern.Column, "%s", message)
+		}
+
+		rule := parsed.perFileRule(glob, files.Pattern.Line)
+		rule.noParent = rule.noParent || files.NoParent
+
+		for _, owner := range files.Owners {
+			rule.owners = reporter.addOwner(rule.owners, owner.Value, owner.Line, owner.Column)
+		}
+	}
+
+	return parsed, reporter.diagnostics
+}
+
+var (
+	yamlErrorLineRE    = regexp.MustCompile(`\Aline (\d+): (.*)\z`)
+	yamlUnknownFieldRE = regexp.MustCompile(`\Afield (\S+) not found in type \S+\z`)
+	yamlNotListRE      = regexp.MustCompile("\\Acannot unmarshal !!\\w+ `(.*)` into \\[\\]\\S+\\z")
+)
+
+// reportYAMLError reports a message from the YAML decoder on the line it names, without the decoder's
+// names for Go types
+func reportYAMLError(reporter *ownersFileReporter, message string) {
+	lineNumber := 0
+
+	if match := yamlErrorLineRE.FindStringSubmatch(message); match != nil {
+		lineNumber, _ = strconv.Atoi(match[1])
+		message = match[2]
+	}
+
+	if match := yamlUnknownFieldRE.FindStringSubmatch(message); match != nil {
+		message = fmt.Sprintf("unknown key '%s'", match[1])
+	}
+
+	if match := yamlNotListRE.FindStringSubmatch(message); match != nil {
+		message = fmt.Sprintf("expected a list, found '%s'", match[1])
+	}
+
+	column := 0
+	if lineNumber > 0 {
+		column = 1
+	}
+
+	reporter.report(SeverityError, lineNumber, column, "invalid YAML: %s", message)
+}
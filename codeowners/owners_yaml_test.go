@@ -0,0 +1,55 @@
+package codeowners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromOwnersFiles_yaml(t *testing.T) {
+	codeowners, err := FromOwnersFiles([]OwnersFile{
+		{Path: "OWNERS", Contents: []byte("root@example.com\n")},
+		{Path: "app/.owners.yml", Contents: []byte(`owners:
+  - "@org/backend"
+include: [/shared/OWNERS]
+files:
+  - pattern: "*.sql"
+    owners: ["@org/dba"]
+  - pattern: BUILD
+    noparent: true
+    owners: ["@org/build"]
+`)},
+		{Path: "app/OWNERS", Contents: []byte("@octocat\n")},
+		{Path: "shared/OWNERS", Contents: []byte("@org/shared\n")},
+		{Path: "secure/.owners.yaml", Contents: []byte("noparent: true\nowners: [\"@org/security\"]\n")},
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, codeowners.Diagnostics())
+
+	// Both files in a directory own it, along with the included file
+	assert.Equal(t, []string{"root@example.com", "@org/backend", "@octocat", "@org/shared"}, codeowners.FindOwners([]byte("app/main.go")))
+	assert.Equal(t, []string{"root@example.com", "@org/backend", "@octocat", "@org/shared", "@org/dba"}, codeowners.FindOwners([]byte("app/schema.sql")))
+	assert.Equal(t, []string{"@org/build"}, codeowners.FindOwners([]byte("app/BUILD")))
+	assert.Equal(t, []string{"@org/security"}, codeowners.FindOwners([]byte("secure/keys.pem")))
+}
+
+func TestFromOwnersFiles_yamlErrors(t *testing.T) {
+	codeowners, err := FromOwnersFiles([]OwnersFile{
+		{Path: ".owners.yml", Contents: []byte("owners: [\"@org/everyone\", \"not an owner\"]\nfiles:\n  - owners: [\"@org/docs\"]\n")},
+		{Path: "app/.owners.yml", Contents: []byte("owners: [\"@org/backend\"\n")},
+		{Path: "lib/.owners.yml", Contents: []byte("owners: \"@org/lib\"\nnoparent: true\n")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Path: ".owners.yml", Line: 0, Column: 0, Severity: SeverityError, Message: "files entry has no pattern"},
+		{Path: ".owners.yml", Line: 1, Column: 27, Severity: SeverityError, Message: "owner 'not an owner' is not a @user, @org/team or email address"},
+		{Path: "app/.owners.yml", Line: 1, Column: 1, Severity: SeverityError, Message: "invalid YAML: did not find expected ',' or ']'"},
+		{Path: "lib/.owners.yml", Line: 1, Column: 1, Severity: SeverityError, Message: "invalid YAML: expected a list, found '@org/lib'"},
+	}, codeowners.Diagnostics())
+
+	// The rest of a file with a mistyped value is still used
+	assert.Equal(t, []string{}, codeowners.FindOwners([]byte("lib/main.go")))
+	assert.Equal(t, []string{"@org/everyone"}, codeowners.FindOwners([]byte("README.md")))
+}
@@ -0,0 +1,112 @@
+package codeowners
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic describes a problem found on a single line of a CODEOWNERS file.
+// Lines with diagnostics are left out of the parsed rules, the same way GitHub
+// keeps enforcing the valid rules and flags the broken ones.
+type Diagnostic struct {
+	// Repository relative path of the OWNERS file the problem is in, empty for a single CODEOWNERS file
+	Path string
+	// 1-based line number, 0 if the problem is with the file as a whole
+	Line int
+	// 1-based byte column of the offending token
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	location := ""
+	if d.Path != "" {
+		location = d.Path + ": "
+	}
+
+	if d.Line == 0 {
+		return fmt.Sprintf("%s%s: %s", location, d.Severity, d.Message)
+	}
+
+	return fmt.Sprintf("%sline %d, column %d: %s: %s", location, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// compareDiagnostics orders diagnostics by file and then by position in the file
+func compareDiagnostics(a, b Diagnostic) int {
+	return cmp.Or(
+		strings.Compare(a.Path, b.Path),
+		cmp.Compare(a.Line, b.Line),
+		cmp.Compare(a.Column, b.Column),
+	)
+}
+
+type token struct {
+	text   string
+	column int
+}
+
+// tokenizeLine splits a CODEOWNERS line into whitespace separated tokens. Runs of spaces
+// and tabs separate tokens, a backslash escapes the following character (e.g. "\ " keeps a
+// space inside a path) and a '#' at the start of a token begins a comment that runs to the
+// end of the line. Escapes are kept in the token text so the pattern compiler can honor them.
+func tokenizeLine(line string) (tokens []token, comment string) {
+	var current strings.Builder
+	start := -1
+	escape := false
+
+	flush := func() {
+		if start != -1 {
+			tokens = append(tokens, token{text: current.String(), column: start + 1})
+			current.Reset()
+			start = -1
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+
+		if escape {
+			escape = false
+			current.WriteByte(ch)
+			continue
+		}
+
+		switch {
+		case ch == ' ' || ch == '\t':
+			flush()
+		case ch == '#' && start == -1:
+			return tokens, strings.TrimSpace(line[i+1:])
+		default:
+			if start == -1 {
+				start = i
+			}
+
+			if ch == '\\' {
+				escape = true
+			}
+
+			current.WriteByte(ch)
+		}
+	}
+
+	flush()
+	return tokens, ""
+}
@@ -0,0 +1,69 @@
+package codeowners
+
+import "slices"
+
+// RuleUsage describes how a single rule applies to a set of files
+type RuleUsage struct {
+	Entry *OwnerEntry
+	// Number of files the rule's pattern matches
+	Matched int
+	// Number of files the rule decides the owners of
+	Won int
+	// The files the rule decides the owners of, in the order they were given
+	WonFiles []string
+	// Line numbers of the later rules in the same section that took over the files this rule matched
+	ShadowedBy []int
+}
+
+// IsDead reports whether the rule doesn't match any of the files
+func (u *RuleUsage) IsDead() bool {
+	return u.Matched == 0
+}
+
+// IsShadowed reports whether the rule matches files but a later rule always wins them
+func (u *RuleUsage) IsShadowed() bool {
+	return u.Matched > 0 && u.Won == 0
+}
+
+// Usage evaluates every rule against the given files, returning one RuleUsage per rule in file order.
+// When the file has sections a rule is only shadowed by later rules in the same section.
+func (co *Codeowners) Usage(files []string) []RuleUsage {
+	usages := make([]RuleUsage, len(co.entries))
+	usageByEntry := map[*OwnerEntry]*RuleUsage{}
+
+	// Entries are stored in match order, fill usages in file order
+	for i := range co.entries {
+		usage := &usages[len(co.entries)-1-i]
+		usage.Entry = &co.entries[i]
+		usageByEntry[usage.Entry] = usage
+	}
+
+	for _, file := range files {
+		// Each section has its own winner, rules only shadow the rules in their own section
+		winners := map[*Section]*OwnerEntry{}
+
+		for _, match := range co.MatchAll([]byte(file)) {
+			usage := usageByEntry[match]
+			usage.Matched++
+
+			winner, found := winners[match.Section]
+
+			if !found {
+				winners[match.Section] = match
+				usage.Won++
+				usage.WonFiles = append(usage.WonFiles, file)
+				continue
+			}
+
+			if !slices.Contains(usage.ShadowedBy, winner.Line) {
+				usage.ShadowedBy = append(usage.ShadowedBy, winner.Line)
+			}
+		}
+	}
+
+	for i := range usages {
+		slices.Sort(usages[i].ShadowedBy)
+	}
+
+	return usages
+}
@@ -0,0 +1,53 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsage(t *testing.T) {
+	codeowners, err := FromReader(bytes.NewBufferString(`docs/ @docs
+removed-dir/ @old-team
+* @everyone
+src/ @src
+src/*.go @go
+`))
+
+	assert.NoError(t, err)
+
+	usages := codeowners.Usage([]string{
+		"docs/readme.md",
+		"src/main.go",
+		"src/util.go",
+		"src/script.sh",
+		"go.mod",
+	})
+
+	type summary struct {
+		line       int
+		matched    int
+		won        int
+		shadowedBy []int
+		dead       bool
+		shadowed   bool
+	}
+
+	actual := []summary{}
+	for _, usage := range usages {
+		actual = append(actual, summary{usage.Entry.Line, usage.Matched, usage.Won, usage.ShadowedBy, usage.IsDead(), usage.IsShadowed()})
+	}
+
+	assert.Equal(t, []summary{
+		{line: 1, matched: 1, won: 0, shadowedBy: []int{3}, shadowed: true},
+		{line: 2, matched: 0, won: 0, dead: true},
+		{line: 3, matched: 5, won: 2, shadowedBy: []int{4, 5}},
+		{line: 4, matched: 3, won: 1, shadowedBy: []int{5}},
+		{line: 5, matched: 2, won: 2},
+	}, actual)
+
+	assert.Equal(t, []string{"src/script.sh"}, usages[3].WonFiles)
+	assert.Equal(t, []string{"src/main.go", "src/util.go"}, usages[4].WonFiles)
+	assert.Empty(t, usages[0].WonFiles)
+}
@@ -2,7 +2,10 @@ module github.com/justindbaur/gh-codeowners
 
 go 1.24.2
 
-require github.com/cli/safeexec v1.0.1
+require (
+	github.com/cli/safeexec v1.0.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/AlecAivazis/survey/v2 v2.3.7 // indirect
@@ -38,5 +41,4 @@ require (
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/term v0.33.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
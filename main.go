@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -64,6 +65,13 @@ func main() {
 				},
 			}, nil
 		},
+		WriteFile: func(filePath string, contents []byte) error {
+			if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+				return err
+			}
+
+			return os.WriteFile(filePath, contents, 0o644)
+		},
 		GetRemoteName: func() (string, error) {
 			remoteOutput, err := exec.Command(gitBin, "remote", "-v").Output()
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -39,6 +40,8 @@ type TestRootCmdOptions struct {
 	In       *bytes.Buffer
 	Out      *bytes.Buffer
 	Err      *bytes.Buffer
+	// Top level directory of the pretend repository
+	Root string
 }
 
 func (testOpts *TestRootCmdOptions) mockTemplateHole(team string, name string, value string) *mock.Call {
@@ -46,17 +49,57 @@ func (testOpts *TestRootCmdOptions) mockTemplateHole(team string, name string, v
 }
 
 func (testOpts *TestRootCmdOptions) mockCodeowners(codeownersContent []string) {
-	testOpts.Mock.On("ReadFile", ".github/CODEOWNERS").
+	testOpts.mockFile(".github/CODEOWNERS", strings.Join(codeownersContent, "\n"))
+	testOpts.mockMissingFile("CODEOWNERS")
+	testOpts.mockMissingFile("docs/CODEOWNERS")
+}
+
+// mockCodeownersAt makes the CODEOWNERS file readable from the given revision
+func (testOpts *TestRootCmdOptions) mockCodeownersAt(ref string, codeownersContent []string) {
+	testOpts.Mock.On("GitExec", []string{"show", ref + ":.github/CODEOWNERS"}).Return([]byte(strings.Join(codeownersContent, "\n")), nil)
+	testOpts.Mock.On("GitExec", []string{"show", ref + ":CODEOWNERS"}).Return([]byte{}, fmt.Errorf("exit status 128"))
+	testOpts.Mock.On("GitExec", []string{"show", ref + ":docs/CODEOWNERS"}).Return([]byte{}, fmt.Errorf("exit status 128"))
+}
+
+// mockFile makes the file at the given path relative to the repository root readable
+func (testOpts *TestRootCmdOptions) mockFile(filePath string, contents string) {
+	testOpts.Mock.On("ReadFile", filepath.Join(testOpts.Root, filePath)).
 		Return(&cmd.File{
-			Reader: bytes.NewBufferString(strings.Join(codeownersContent, "\n")),
+			Reader: bytes.NewBufferString(contents),
 			Close:  func() error { return nil },
 		}, nil)
 }
 
+func (testOpts *TestRootCmdOptions) mockMissingFile(filePath string) {
+	testOpts.Mock.On("ReadFile", filepath.Join(testOpts.Root, filePath)).
+		Return((*cmd.File)(nil), os.ErrNotExist)
+}
+
+// mockCurrentDirectory pretends the command is run from the given directory relative to the repository root
+func (testOpts *TestRootCmdOptions) mockCurrentDirectory(prefix string) {
+	testOpts.Mock.On("GitExec", []string{"rev-parse", "--show-prefix"}).Return(fmt.Appendf(nil, "%s\n", prefix), nil)
+}
+
 func (testOpts *TestRootCmdOptions) mockWorkingDirectory(files []string) {
 	testOpts.Mock.
-		On("GitExec", []string{"--no-pager", "diff", "--name-only"}).
-		Return([]byte(strings.Join(files, "\n")), nil)
+		On("GitExec", []string{"--no-pager", "diff", "--name-status", "-M", "-z"}).
+		Return(nameStatusOutput(files), nil)
+}
+
+// nameStatusOutput builds `git diff --name-status -z` output where every file is modified
+func nameStatusOutput(files []string) []byte {
+	output := []byte{}
+
+	for _, file := range files {
+		output = fmt.Appendf(output, "M\x00%s\x00", file)
+	}
+
+	return output
+}
+
+// addArgs builds the arguments git is called with to stage the given paths
+func (testOpts *TestRootCmdOptions) addArgs(paths ...string) []string {
+	return append([]string{"-C", testOpts.Root, "--literal-pathspecs", "add", "--"}, paths...)
 }
 
 func (testOpts *TestRootCmdOptions) toActual() *cmd.RootCmdOptions {
@@ -68,6 +111,10 @@ func (testOpts *TestRootCmdOptions) toActual() *cmd.RootCmdOptions {
 			args := testOpts.Mock.MethodCalled("ReadFile", filePath)
 			return args.Get(0).(*cmd.File), args.Error(1)
 		},
+		WriteFile: func(filePath string, contents []byte) error {
+			args := testOpts.Mock.MethodCalled("WriteFile", filePath, string(contents))
+			return args.Error(0)
+		},
 		GitExec: func(arg ...string) ([]byte, error) {
 			args := testOpts.Mock.MethodCalled("GitExec", arg)
 			return args.Get(0).([]byte), args.Error(1)
@@ -88,18 +135,25 @@ func (testOpts *TestRootCmdOptions) toActual() *cmd.RootCmdOptions {
 	}
 }
 
-func newTestRootOpts() *TestRootCmdOptions {
-	return &TestRootCmdOptions{
+func newTestRootOpts(t *testing.T) *TestRootCmdOptions {
+	tempDir := t.TempDir()
+
+	testOpts := &TestRootCmdOptions{
 		In:       bytes.NewBuffer([]byte{}),
 		Out:      bytes.NewBuffer([]byte{}),
 		Err:      bytes.NewBuffer([]byte{}),
 		Mock:     &mock.Mock{},
 		Prompter: newMockPrompter(),
+		Root:     tempDir,
 	}
+
+	testOpts.Mock.On("GitExec", []string{"rev-parse", "--show-toplevel"}).Return(fmt.Appendf(nil, "%s\n", tempDir), nil)
+
+	return testOpts
 }
 
 func TestMainCoreReport(t *testing.T) {
-	testOpts := newTestRootOpts()
+	testOpts := newTestRootOpts(t)
 
 	testOpts.mockCodeowners([]string{
 		"test-dir @team-1",
@@ -118,7 +172,7 @@ func TestMainCoreReport(t *testing.T) {
 }
 
 func TestMainCoreStage(t *testing.T) {
-	testOpts := newTestRootOpts()
+	testOpts := newTestRootOpts(t)
 
 	testOpts.mockCodeowners([]string{
 		"test-dir @team-1",
@@ -130,7 +184,7 @@ func TestMainCoreStage(t *testing.T) {
 	})
 
 	testOpts.Mock.
-		On("GitExec", []string{"add", "test-dir/test-file.txt"}).
+		On("GitExec", testOpts.addArgs("test-dir/test-file.txt")).
 		Return([]byte{}, nil)
 
 	err := mainCore(testOpts.toActual(), []string{"stage", "@team-1"})
@@ -141,7 +195,7 @@ func TestMainCoreStage(t *testing.T) {
 }
 
 func TestMainCoreAutoPR(t *testing.T) {
-	testOpts := newTestRootOpts()
+	testOpts := newTestRootOpts(t)
 
 	testOpts.Prompter.On("Input", "What branch template do you want?", "").Return("branch-{{ .Input \"Safe Name\"}}", nil)
 	testOpts.Prompter.On("Input", "What commit/PR title template do you want?", "Files for {{ .TeamId }}").Return("Do work for {{ .Input \"Safe Name\" }}", nil)
@@ -149,17 +203,15 @@ func TestMainCoreAutoPR(t *testing.T) {
 	testOpts.mockTemplateHole("1", "Safe Name", "one")
 	testOpts.mockTemplateHole("2", "Safe Name", "two")
 
-	testOpts.mockCodeowners([]string{
+	// Ownership comes from the branch the PR's target, not the working tree
+	testOpts.Mock.On("GhExec", []string{"repo", "view", "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name"}).
+		Return(*bytes.NewBufferString("main\n"), *bytes.NewBuffer([]byte{}), nil)
+
+	testOpts.mockCodeownersAt("origin/main", []string{
 		"test-dir @team-1",
 		"other-dir @team-2",
 	})
 
-	tempDir, _ := os.MkdirTemp("", "test")
-
-	testOpts.Mock.On("GitExec", []string{
-		"rev-parse",
-		"--show-toplevel"}).Return(fmt.Appendf(nil, "%s\n", tempDir), nil)
-
 	testOpts.Mock.On("ReadFile", "./.github/PULL_REQUEST_TEMPLATE.md").Return(&cmd.File{
 		Reader: bytes.NewBufferString("My PR template!"),
 		Close:  func() error { return nil },
@@ -170,8 +222,8 @@ func TestMainCoreAutoPR(t *testing.T) {
 		"other-dir/file.txt",
 	})
 
-	testOpts.Mock.On("GitExec", []string{"add", "test-dir/test-file.txt"}).Return([]byte{}, nil)
-	testOpts.Mock.On("GitExec", []string{"add", "other-dir/file.txt"}).Return([]byte{}, nil)
+	testOpts.Mock.On("GitExec", testOpts.addArgs("test-dir/test-file.txt")).Return([]byte{}, nil)
+	testOpts.Mock.On("GitExec", testOpts.addArgs("other-dir/file.txt")).Return([]byte{}, nil)
 
 	testOpts.Mock.On("GetRemoteName").Return("origin", nil)
 
@@ -205,20 +257,38 @@ func TestMainCoreAutoPR(t *testing.T) {
 }
 
 func TestMainCoreAutoPR_withArgsMakesTwoPRS(t *testing.T) {
-	opts := setupAutoPRTest("dir-1 @team-1\ndir-2 @team-2\n", "dir-1/test.txt\ndir-2/test.txt\n")
+	opts := setupAutoPRTest(t, "dir-1 @team-1\ndir-2 @team-2\n", "dir-1/test.txt\ndir-2/test.txt\n")
 
 	opts.mockTemplateHole("@team-1", "Team Name", "one")
 	opts.mockTemplateHole("@team-2", "Team Name", "two")
 
 	err := mainCore(opts.toActual(), []string{"auto-pr", "--draft", "--commit", "commit-{{ .Name }}", "--branch", "branch/{{ .Name }}"})
 
-	opts.Mock.AssertNumberOfCalls(t, "GhExec", 2)
+	// One call to find the default branch, then one per PR
+	opts.Mock.AssertNumberOfCalls(t, "GhExec", 3)
+
+	assert.NoError(t, err)
+}
+
+func TestMainCoreAutoPR_intentionallyUnowned(t *testing.T) {
+	opts := setupAutoPRTest(t, "dir-1 @team-1\ndir-2 @team-2\ngenerated/\n", "dir-1/test.txt\ndir-2/test.txt\ngenerated/api.txt\nother/test.txt\n")
+
+	// Files without any rule and files with a rule that has no owners are asked about separately,
+	// both are put onto their own PR
+	opts.Prompter.On("Select", "Choose where to put 1 unowned files", "", mock.Anything).Return(2, nil)
+	opts.Prompter.On("Select", "Choose where to put 1 files CODEOWNERS leaves unowned on purpose", "", mock.Anything).Return(3, nil)
+
+	err := mainCore(opts.toActual(), []string{"auto-pr", "--draft", "--commit", "commit-{{ .Name }}", "--branch", "branch/{{ .Name }}"})
 
 	assert.NoError(t, err)
+	opts.Prompter.AssertNumberOfCalls(t, "Select", 2)
+
+	// One call to find the default branch, then one per PR
+	opts.Mock.AssertNumberOfCalls(t, "GhExec", 4)
 }
 
 func TestMainCoreAutoPR_help(t *testing.T) {
-	opts := setupAutoPRTest("", "")
+	opts := setupAutoPRTest(t, "", "")
 
 	err := mainCore(opts.toActual(), []string{"auto-pr", "--help"})
 
@@ -228,7 +298,7 @@ func TestMainCoreAutoPR_help(t *testing.T) {
 }
 
 func TestMainCoreAutoPR_helpLong(t *testing.T) {
-	opts := setupAutoPRTest("", "")
+	opts := setupAutoPRTest(t, "", "")
 
 	err := mainCore(opts.toActual(), []string{"help", "auto-pr"})
 
@@ -237,27 +307,21 @@ func TestMainCoreAutoPR_helpLong(t *testing.T) {
 	assert.NotEmpty(t, helpOutput)
 }
 
-func setupAutoPRTest(codeownersFile string, workingTree string) *TestRootCmdOptions {
-	testOpts := newTestRootOpts()
+func setupAutoPRTest(t *testing.T, codeownersFile string, workingTree string) *TestRootCmdOptions {
+	testOpts := newTestRootOpts(t)
 
-	testOpts.Mock.On("ReadFile", ".github/CODEOWNERS").Return(&cmd.File{
-		Reader: bytes.NewBufferString(codeownersFile),
-		Close:  func() error { return nil },
-	}, nil)
+	testOpts.Mock.On("GhExec", []string{"repo", "view", "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name"}).
+		Return(*bytes.NewBufferString("main\n"), *bytes.NewBuffer([]byte{}), nil)
+
+	testOpts.mockCodeownersAt("origin/main", []string{codeownersFile})
 
 	testOpts.Mock.On("ReadFile", "./.github/PULL_REQUEST_TEMPLATE.md").Return(&cmd.File{
 		Reader: bytes.NewBufferString("My PR template!"),
 		Close:  func() error { return nil },
 	}, nil)
 
-	tempDir, _ := os.MkdirTemp("", "test")
-
 	testOpts.Mock.On("GetRemoteName").Return("origin", nil)
 
-	testOpts.Mock.On("GitExec", []string{
-		"rev-parse",
-		"--show-toplevel"}).Return(fmt.Appendf(nil, "%s\n", tempDir), nil)
-
 	testOpts.Prompter.On("Input", "Enter the path to the file containing your PR template", "./.github/PULL_REQUEST_TEMPLATE.md").Return("./.github/PULL_REQUEST_TEMPLATE.md", nil)
 
 	testOpts.Mock.On("AskOne", "", mock.Anything).Run(func(args mock.Arguments) {
@@ -265,7 +329,8 @@ func setupAutoPRTest(codeownersFile string, workingTree string) *TestRootCmdOpti
 		*contents = "My PR template!\nFor {slug}: {Team Name}"
 	}).Return(nil)
 
-	testOpts.Mock.On("GitExec", []string{"--no-pager", "diff", "--name-only"}).Return([]byte(workingTree), nil)
+	testOpts.Mock.On("GitExec", []string{"--no-pager", "diff", "--name-status", "-M", "-z"}).
+		Return(nameStatusOutput(strings.Fields(workingTree)), nil)
 
 	// For anything else just pretend success
 	testOpts.Mock.On("GitExec", mock.Anything).Return([]byte{}, nil)
@@ -274,3 +339,1459 @@ func setupAutoPRTest(codeownersFile string, workingTree string) *TestRootCmdOpti
 
 	return testOpts
 }
+
+func TestMainCoreExplain(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @default",
+		"test-dir @team-1 # Team one",
+		"generated/",
+	})
+
+	testOpts.mockCurrentDirectory("")
+
+	err := mainCore(testOpts.toActual(), []string{"explain", "--all", "test-dir/test-file.txt", "other-dir/file.txt", "generated/api.go"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `test-dir/test-file.txt
+  Owners: @team-1
+  Rule: line 2: test-dir @team-1 # Team one
+  Overridden rules:
+    line 1: * @default
+other-dir/file.txt
+  Owners: @default
+  Rule: line 1: * @default
+generated/api.go
+  Owners: none, the rule intentionally leaves the file unowned
+  Rule: line 3: generated/
+  Overridden rules:
+    line 1: * @default
+`, testOpts.Out.String())
+}
+
+func TestMainCoreLint(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @org/everyone",
+		"!docs @org/docs",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"lint"})
+
+	assert.EqualError(t, err, "found 1 errors in .github/CODEOWNERS")
+	assert.Equal(t, ".github/CODEOWNERS:2:1: error: pattern '!docs' uses '!' negation which GitHub does not support\n", testOpts.Out.String())
+}
+
+func TestMainCoreUnused(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"test-dir @team-1",
+		"removed-dir @team-2",
+		"* @default",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("test-dir/test-file.txt\x00README.md\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"unused"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Dead rules, these match no tracked files:
+  line 2: removed-dir @team-2
+Shadowed rules, these match files but a later rule always wins:
+  line 1: test-dir @team-1 (overridden by line 3)
+1 dead and 1 shadowed rules out of 3
+`, testOpts.Out.String())
+}
+
+func TestMainCoreCoverage(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"test-dir @team-1",
+		"*.md @team-2 @team-1",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("test-dir/test-file.txt\x00README.md\x00tools/build.sh\x00tools/lint.sh\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"coverage"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Tracked files: 4
+Owned files: 2 (50.0%)
+Unowned files: 2 (50.0%)
+
+Files per owner:
+  @team-1: 2
+  @team-2: 1
+
+Largest unowned directories:
+  tools/: 2
+`, testOpts.Out.String())
+}
+
+func TestMainCoreCoverage_distinctOwners(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/api/ @org/core @org/core",
+		"/docs/ @Org/Docs",
+		"*.md @org/docs",
+		"/docs/generated/",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("api/main.go\x00api/server.go\x00docs/setup.txt\x00README.md\x00docs/generated/api.md\x00tools/build.sh\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"coverage"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Tracked files: 6
+Owned files: 4 (66.7%)
+Unowned files: 1 (16.7%)
+Intentionally unowned files: 1 (16.7%)
+
+Files per owner:
+  @Org/Docs: 2
+  @org/core: 2
+
+Largest unowned directories:
+  tools/: 1
+`, testOpts.Out.String())
+}
+
+func TestMainCoreCoverage_topZero(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"test-dir @team-1",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("test-dir/test-file.txt\x00tools/build.sh\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"coverage", "--top", "0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Tracked files: 2
+Owned files: 1 (50.0%)
+Unowned files: 1 (50.0%)
+
+Files per owner:
+  @team-1: 1
+`, testOpts.Out.String())
+}
+
+func TestMainCoreReport_formats(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: "table",
+			expected: `File 'shared/b.txt' is owned by multiple teams @team-1, @team-2
+@team-1: 2
+@team-2: 1
+Files that are unowned: 1
+Files that are intentionally unowned: 1
+`,
+		},
+		{
+			format: "json",
+			expected: `{
+  "files": [
+    {
+      "path": "README.md",
+      "status": "modified",
+      "owners": [],
+      "rule": null
+    },
+    {
+      "path": "other-dir/file.txt",
+      "status": "modified",
+      "owners": [
+        "@team-2"
+      ],
+      "rule": {
+        "line": 2,
+        "pattern": "other-dir"
+      }
+    },
+    {
+      "path": "shared/b.txt",
+      "status": "modified",
+      "owners": [
+        "@team-1",
+        "@team-2"
+      ],
+      "rule": {
+        "line": 3,
+        "pattern": "shared/"
+      }
+    },
+    {
+      "path": "test-dir/a.txt",
+      "status": "modified",
+      "owners": [
+        "@team-1"
+      ],
+      "rule": {
+        "line": 1,
+        "pattern": "test-dir"
+      }
+    },
+    {
+      "path": "test-dir/b.txt",
+      "status": "modified",
+      "owners": [
+        "@team-1"
+      ],
+      "rule": {
+        "line": 1,
+        "pattern": "test-dir"
+      }
+    },
+    {
+      "path": "test-dir/generated/c.txt",
+      "status": "modified",
+      "owners": [],
+      "rule": {
+        "line": 4,
+        "pattern": "test-dir/generated/"
+      }
+    }
+  ],
+  "owners": [
+    {
+      "owner": "@team-1",
+      "files": 2
+    },
+    {
+      "owner": "@team-2",
+      "files": 1
+    }
+  ],
+  "unownedFiles": 1,
+  "intentionallyUnownedFiles": 1,
+  "multipleOwnerFiles": [
+    {
+      "path": "shared/b.txt",
+      "status": "modified",
+      "owners": [
+        "@team-1",
+        "@team-2"
+      ],
+      "rule": {
+        "line": 3,
+        "pattern": "shared/"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			format: "csv",
+			expected: `path,old_path,status,owners,rule_line,rule_pattern,old_rule_line,old_rule_pattern
+README.md,,modified,,,,,
+other-dir/file.txt,,modified,@team-2,2,other-dir,,
+shared/b.txt,,modified,@team-1 @team-2,3,shared/,,
+test-dir/a.txt,,modified,@team-1,1,test-dir,,
+test-dir/b.txt,,modified,@team-1,1,test-dir,,
+test-dir/generated/c.txt,,modified,,4,test-dir/generated/,,
+`,
+		},
+		{
+			format: "markdown",
+			expected: "| Owner | Files |\n| --- | --- |\n| @team-1 | 2 |\n| @team-2 | 1 |\n| Multiple owners | 1 |\n| Unowned | 1 |\n| Intentionally unowned | 1 |\n\n" +
+				"### Files with multiple owners\n\n| File | Owners |\n| --- | --- |\n| `shared/b.txt` | @team-1 @team-2 |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			testOpts := newTestRootOpts(t)
+
+			testOpts.mockCodeowners([]string{
+				"test-dir @team-1",
+				"other-dir @team-2",
+				"shared/ @team-1 @team-2",
+				"test-dir/generated/",
+			})
+
+			testOpts.mockWorkingDirectory([]string{
+				"test-dir/b.txt",
+				"shared/b.txt",
+				"README.md",
+				"other-dir/file.txt",
+				"test-dir/a.txt",
+				"test-dir/generated/c.txt",
+			})
+
+			err := mainCore(testOpts.toActual(), []string{"report", "--format", tt.format})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, testOpts.Out.String())
+		})
+	}
+}
+
+func TestMainCoreReport_changeSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		gitExec map[string]string
+		stdin   string
+	}{
+		{
+			name: "Staged",
+			args: []string{"--staged"},
+			gitExec: map[string]string{
+				"--no-pager diff --name-status -M -z --cached": "M\x00test-dir/test-file.txt\x00",
+			},
+		},
+		{
+			name: "Base ref",
+			args: []string{"--base", "main"},
+			gitExec: map[string]string{
+				"--no-pager diff --name-status -M -z main...HEAD": "M\x00test-dir/test-file.txt\x00",
+			},
+		},
+		{
+			name: "Rev range",
+			args: []string{"--base", "v1.0..v2.0"},
+			gitExec: map[string]string{
+				"--no-pager diff --name-status -M -z v1.0..v2.0": "M\x00test-dir/test-file.txt\x00",
+			},
+		},
+		{
+			name: "Include untracked",
+			args: []string{"--include-untracked"},
+			gitExec: map[string]string{
+				"--no-pager diff --name-status -M -z":               "",
+				"-C {root} ls-files --others --exclude-standard -z": "test-dir/test-file.txt\x00",
+			},
+		},
+		{
+			name:  "Files from stdin",
+			args:  []string{"--files-from", "-"},
+			stdin: "test-dir/test-file.txt\n",
+		},
+		{
+			name:  "NUL separated files from stdin",
+			args:  []string{"--files-from", "-"},
+			stdin: "test-dir/test-file.txt\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testOpts := newTestRootOpts(t)
+			testOpts.In.WriteString(tt.stdin)
+
+			testOpts.mockCodeowners([]string{
+				"test-dir @team-1",
+			})
+
+			for args, output := range tt.gitExec {
+				args = strings.ReplaceAll(args, "{root}", testOpts.Root)
+				testOpts.Mock.On("GitExec", strings.Split(args, " ")).Return([]byte(output), nil)
+			}
+
+			err := mainCore(testOpts.toActual(), append([]string{"report"}, tt.args...))
+
+			assert.NoError(t, err)
+			assert.Equal(t, "@team-1: 1\n", testOpts.Out.String())
+		})
+	}
+}
+
+func TestMainCoreReport_filesFromExclusive(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--files-from", "-", "--staged"})
+
+	assert.ErrorContains(t, err, "none of the others can be")
+}
+
+func TestMainCoreReport_stagedAndBaseExclusive(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--staged", "--base", "main"})
+
+	assert.ErrorContains(t, err, "[base staged] were all set")
+}
+
+func TestMainCore_changeFlagsOnlyOnChangeCommands(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	err := mainCore(testOpts.toActual(), []string{"lint", "--staged"})
+
+	assert.EqualError(t, err, "unknown flag: --staged")
+}
+
+func TestMainCore_ownerKindOnlyOnFilteringCommands(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	err := mainCore(testOpts.toActual(), []string{"coverage", "--owner-kind", "team"})
+
+	assert.EqualError(t, err, "unknown flag: --owner-kind")
+}
+
+func TestMainCoreReport_diffError(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"test-dir @team-1"})
+	testOpts.Mock.
+		On("GitExec", []string{"--no-pager", "diff", "--name-status", "-M", "-z", "nope...HEAD"}).
+		Return([]byte{}, fmt.Errorf("exit status 128"))
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--base", "nope"})
+
+	assert.EqualError(t, err, "error getting changed files: error running git diff: exit status 128")
+}
+
+func TestMainCoreStage_renameBetweenTeams(t *testing.T) {
+	for _, team := range []string{"@team-1", "@team-2"} {
+		t.Run(team, func(t *testing.T) {
+			testOpts := newTestRootOpts(t)
+
+			testOpts.mockCodeowners([]string{
+				"test-dir @team-1",
+				"other-dir @team-2",
+			})
+
+			testOpts.Mock.
+				On("GitExec", []string{"--no-pager", "diff", "--name-status", "-M", "-z"}).
+				Return([]byte("R100\x00test-dir/file.txt\x00other-dir/file.txt\x00D\x00test-dir/removed.txt\x00"), nil)
+
+			testOpts.Mock.On("GitExec", testOpts.addArgs("other-dir/file.txt", "test-dir/file.txt")).Return([]byte{}, nil)
+			testOpts.Mock.On("GitExec", testOpts.addArgs("test-dir/removed.txt")).Return([]byte{}, nil)
+
+			err := mainCore(testOpts.toActual(), []string{"stage", team})
+
+			assert.NoError(t, err)
+
+			expected := "Staged: test-dir/file.txt -> other-dir/file.txt\n"
+			if team == "@team-1" {
+				expected += "Staged: test-dir/removed.txt\n"
+			}
+
+			assert.Equal(t, expected, testOpts.Out.String())
+		})
+	}
+}
+
+func TestMainCoreReport_renameCSV(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"test-dir @team-1",
+		"other-dir @team-2",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"--no-pager", "diff", "--name-status", "-M", "-z"}).
+		Return([]byte("R100\x00test-dir/file.txt\x00other-dir/file.txt\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--format", "csv"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `path,old_path,status,owners,rule_line,rule_pattern,old_rule_line,old_rule_pattern
+other-dir/file.txt,test-dir/file.txt,renamed,@team-2 @team-1,2,other-dir,1,test-dir
+`, testOpts.Out.String())
+}
+
+func TestMainCoreStage_unusualPaths(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/assets/日本語/ @team-l10n",
+		"/docs/user\\ guide/ @team-l10n",
+		"*.txt @team-l10n",
+		"/src/ @team-other",
+	})
+
+	files := []string{
+		"assets/日本語/ファイル.png",
+		"docs/user guide/\"quoted\" name.md",
+		"notes/line\nbreak.txt",
+		"-starts-with-dash.txt",
+		"src/main.go",
+	}
+
+	testOpts.mockWorkingDirectory(files)
+
+	for _, file := range files[:4] {
+		testOpts.Mock.On("GitExec", testOpts.addArgs(file)).Return([]byte{}, nil)
+	}
+
+	err := mainCore(testOpts.toActual(), []string{"stage", "@team-l10n"})
+
+	assert.NoError(t, err)
+	testOpts.Mock.AssertNotCalled(t, "GitExec", testOpts.addArgs("src/main.go"))
+	assert.Equal(t, "Staged: assets/日本語/ファイル.png\nStaged: docs/user guide/\"quoted\" name.md\nStaged: notes/line\nbreak.txt\nStaged: -starts-with-dash.txt\n", testOpts.Out.String())
+}
+
+func TestMainCoreCoverage_unusualPaths(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/assets/ @team-1",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("assets/café menu.txt\x00assets/new\nline.txt\x00ünowned/日本語.txt\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"coverage"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Tracked files: 3
+Owned files: 2 (66.7%)
+Unowned files: 1 (33.3%)
+
+Files per owner:
+  @team-1: 2
+
+Largest unowned directories:
+  ünowned/: 1
+`, testOpts.Out.String())
+}
+
+func TestMainCoreExplain_fromSubdirectory(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/test-dir/nested/ @team-1",
+	})
+
+	testOpts.mockCurrentDirectory("test-dir/")
+
+	err := mainCore(testOpts.toActual(), []string{"explain", "nested/file.txt", filepath.Join(testOpts.Root, "test-dir/nested/other.txt")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `test-dir/nested/file.txt
+  Owners: @team-1
+  Rule: line 1: /test-dir/nested/ @team-1
+test-dir/nested/other.txt
+  Owners: @team-1
+  Rule: line 1: /test-dir/nested/ @team-1
+`, testOpts.Out.String())
+}
+
+func TestMainCoreReport_multipleCodeownersFiles(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockFile(".github/CODEOWNERS", "test-dir @team-1")
+	testOpts.mockFile("CODEOWNERS", "test-dir @team-2")
+	testOpts.mockFile("docs/CODEOWNERS", "test-dir @team-3")
+
+	testOpts.mockWorkingDirectory([]string{
+		"test-dir/test-file.txt",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "@team-1: 1\n", testOpts.Out.String())
+	assert.Equal(t, "warning: found multiple CODEOWNERS files, GitHub only uses .github/CODEOWNERS and ignores CODEOWNERS, docs/CODEOWNERS\n", testOpts.Err.String())
+}
+
+func TestMainCoreLint_codeownersFlag(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCurrentDirectory("sub-dir/")
+
+	testOpts.Mock.On("ReadFile", "OWNERS.txt").Return(&cmd.File{
+		Reader: bytes.NewBufferString("/sub-dir/ @team-1\n"),
+		Close:  func() error { return nil },
+	}, nil)
+
+	err := mainCore(testOpts.toActual(), []string{"lint", "--codeowners", "OWNERS.txt"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sub-dir/OWNERS.txt: no problems found\n", testOpts.Out.String())
+}
+
+func TestMainCoreReport_codeownersRef(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	// The working tree has a local edit that shouldn't be used
+	testOpts.mockCodeowners([]string{"test-dir @team-2"})
+	testOpts.mockCodeownersAt("main", []string{"test-dir @team-1"})
+
+	testOpts.mockWorkingDirectory([]string{
+		"test-dir/test-file.txt",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--codeowners-ref", "main"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "@team-1: 1\n", testOpts.Out.String())
+}
+
+func TestMainCoreReport_codeownersRefMissing(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.Mock.On("GitExec", mock.MatchedBy(func(args []string) bool {
+		return args[0] == "show"
+	})).Return([]byte{}, fmt.Errorf("exit status 128"))
+
+	testOpts.mockWorkingDirectory([]string{
+		"test-dir/test-file.txt",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--codeowners-ref", "v1.0.0"})
+
+	assert.EqualError(t, err, "error getting codeowners info: could not locate a CODEOWNERS file in 'v1.0.0'")
+}
+
+func TestMainCoreDiffOwnership(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeownersAt("main", []string{
+		"/api/ @team-1",
+	})
+
+	testOpts.mockCodeownersAt("HEAD", []string{
+		"/api/ @team-1",
+		"/api/billing/ @team-2",
+		"/docs/ @team-3",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("api/main.go\x00api/billing/invoice.go\x00docs/index.md\x00README.md\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"diff-ownership", "main", "HEAD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Files that gained owners:
+  docs/index.md: @team-3
+Files that switched owners:
+  api/billing/invoice.go: @team-1 -> @team-2
+
+Changes per owner:
+  @team-1: +0 -1
+  @team-2: +1 -0
+  @team-3: +1 -0
+2 of 4 files changed owners
+`, testOpts.Out.String())
+}
+
+func TestMainCoreSimulate(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @default",
+		"/services/ @services",
+		"/docs/ @docs",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("services/billing/invoice.go\x00services/auth/login.go\x00docs/index.md\x00README.md\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"simulate", "--add", "/services/billing/ @billing", "--remove", "3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Removing line 3: /docs/ @docs
+Adding line 3: /services/billing/ @billing
+
+Files that switched owners:
+  services/billing/invoice.go: @services -> @billing
+  docs/index.md: @docs -> @default
+
+Changes per owner:
+  @billing: +1 -0
+  @default: +1 -0
+  @docs: +0 -1
+  @services: +0 -1
+2 of 4 files would change owners
+`, testOpts.Out.String())
+}
+
+func TestMainCoreSimulate_line(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @default",
+		"/services/ @services",
+		"/services/billing/legacy/ @legacy",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("services/billing/invoice.go\x00services/billing/legacy/old.go\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"simulate", "--add", "/services/billing/ @billing", "--line", "3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Adding line 3: /services/billing/ @billing
+
+Files that switched owners:
+  services/billing/invoice.go: @services -> @billing
+
+Changes per owner:
+  @billing: +1 -0
+  @services: +0 -1
+1 of 2 files would change owners
+`, testOpts.Out.String())
+}
+
+func TestMainCoreStage_ignoresCase(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"test-dir @my-org/Team-1",
+	})
+
+	testOpts.mockWorkingDirectory([]string{
+		"test-dir/test-file.txt",
+	})
+
+	testOpts.Mock.
+		On("GitExec", testOpts.addArgs("test-dir/test-file.txt")).
+		Return([]byte{}, nil)
+
+	err := mainCore(testOpts.toActual(), []string{"stage", "@My-Org/team-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Staged: test-dir/test-file.txt\n", testOpts.Out.String())
+}
+
+func TestMainCoreStage_invalidOwner(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	err := mainCore(testOpts.toActual(), []string{"stage", "team-1"})
+
+	assert.EqualError(t, err, "owner 'team-1' is not a @user, @org/team or email address")
+}
+
+func TestMainCoreReport_ownerKind(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"api/ @org/api @octocat",
+		"web/ @Org/Web",
+		"docs/ @org/web docs@example.com",
+	})
+
+	testOpts.mockWorkingDirectory([]string{
+		"api/main.go",
+		"web/index.html",
+		"docs/index.md",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--owner-kind", "team"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "@Org/Web: 2\n@org/api: 1\n", testOpts.Out.String())
+}
+
+func TestMainCoreReport_gitlabSections(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @org/everyone",
+		"[Documentation][2] @org/docs",
+		"docs/",
+		"^[Translations]",
+		"docs/locales/ @org/l10n",
+	})
+
+	testOpts.mockWorkingDirectory([]string{
+		"docs/index.md",
+		"docs/locales/fr.md",
+		"src/main.go",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `File 'docs/index.md' is owned by multiple teams @org/everyone, @org/docs
+File 'docs/locales/fr.md' is owned by multiple teams @org/everyone, @org/docs, @org/l10n
+@org/everyone: 1
+[Default]
+  @org/everyone: 3
+[Documentation (2 approvals)]
+  @org/docs: 2
+[Translations (optional)]
+  @org/l10n: 1
+`, testOpts.Out.String())
+}
+
+func TestMainCoreAutoPR_groupBySection(t *testing.T) {
+	opts := setupAutoPRTest(t, "* @org/everyone\n[Docs] @org/docs\ndocs/\n[Backend]\napp/ @org/backend\n", "docs/index.md\napp/main.go\nREADME.md\n")
+
+	opts.mockTemplateHole("Docs", "Team Name", "one")
+	opts.mockTemplateHole("Backend", "Team Name", "two")
+	opts.mockTemplateHole("Default", "Team Name", "three")
+
+	err := mainCore(opts.toActual(), []string{"auto-pr", "--group-by", "section", "--draft", "--commit", "commit-{{ .TeamId }}", "--branch", "branch/{{ .TeamId }}"})
+
+	assert.NoError(t, err)
+
+	// One PR per section
+	opts.Mock.AssertCalled(t, "GitExec", []string{"checkout", "-b", "branch/Docs"})
+	opts.Mock.AssertCalled(t, "GitExec", []string{"checkout", "-b", "branch/Backend"})
+	opts.Mock.AssertCalled(t, "GitExec", []string{"checkout", "-b", "branch/Default"})
+	opts.Mock.AssertCalled(t, "GitExec", opts.addArgs("docs/index.md"))
+	opts.Mock.AssertNumberOfCalls(t, "GhExec", 4)
+}
+
+func TestMainCoreReport_dialectFlag(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	// GitLab checks the root before docs/ and .gitlab/, and never .github/
+	testOpts.mockFile("CODEOWNERS", "test-dir @team-2")
+	testOpts.mockMissingFile("docs/CODEOWNERS")
+	testOpts.mockFile(".gitlab/CODEOWNERS", "test-dir @team-3")
+
+	testOpts.mockWorkingDirectory([]string{
+		"test-dir/test-file.txt",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--dialect", "gitlab"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "@team-2: 1\n", testOpts.Out.String())
+	assert.Equal(t, "warning: found multiple CODEOWNERS files, GitLab only uses CODEOWNERS and ignores .gitlab/CODEOWNERS\n", testOpts.Err.String())
+}
+
+func TestMainCoreReport_gerritOwnersFiles(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	// Without a CODEOWNERS file anywhere, a root OWNERS file means the repository uses Gerrit OWNERS files
+	for _, location := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS", ".bitbucket/CODEOWNERS"} {
+		testOpts.mockMissingFile(location)
+	}
+
+	testOpts.mockFile("OWNERS", "root@example.com\n")
+	testOpts.mockFile("app/OWNERS", "set noparent\napp@example.com\nper-file *.sql=dba@example.com\n")
+	testOpts.Mock.On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z", "--cached", "--others", "--exclude-standard"}).
+		Return([]byte("OWNERS\x00README.md\x00app/OWNERS\x00app/main.go\x00"), nil)
+
+	testOpts.mockWorkingDirectory([]string{
+		"README.md",
+		"app/main.go",
+		"app/schema.sql",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"report", "--format", "csv"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `path,old_path,status,owners,rule_line,rule_pattern,old_rule_line,old_rule_pattern
+README.md,,modified,root@example.com,1,*,,
+app/main.go,,modified,app@example.com,2,/app/,,
+app/schema.sql,,modified,app@example.com dba@example.com,3,/app/*.sql,,
+`, testOpts.Out.String())
+	assert.Empty(t, testOpts.Err.String())
+}
+
+func TestMainCoreLint_gerritOwnersFiles(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockFile("OWNERS", "root@example.com\n")
+	testOpts.mockFile("app/OWNERS", "app@example.com\nper-file *.sql\n")
+	testOpts.Mock.On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z", "--cached", "--others", "--exclude-standard"}).
+		Return([]byte("OWNERS\x00app/OWNERS\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"lint", "--dialect", "gerrit"})
+
+	assert.EqualError(t, err, "found 1 errors in OWNERS")
+	assert.Equal(t, "app/OWNERS:2:1: error: invalid per-file line 'per-file *.sql', expected per-file glob=owners\n", testOpts.Out.String())
+}
+
+func TestMainCoreStage_bitbucketGroups(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	for _, location := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"} {
+		testOpts.mockMissingFile(location)
+	}
+
+	testOpts.mockFile(".bitbucket/CODEOWNERS", "@@@Backend @org/api @octocat\napp/ @@Backend\nCheck(@@Backend >= 2)\n")
+	testOpts.mockWorkingDirectory([]string{
+		"app/main.go",
+		"README.md",
+	})
+	testOpts.Mock.On("GitExec", testOpts.addArgs("app/main.go")).Return([]byte{}, nil)
+
+	err := mainCore(testOpts.toActual(), []string{"stage", "@org/api"})
+
+	assert.NoError(t, err)
+	testOpts.Mock.AssertCalled(t, "GitExec", testOpts.addArgs("app/main.go"))
+}
+
+// mockOwnersFiles makes the given OWNERS files, and only them, readable from the working tree
+func (testOpts *TestRootCmdOptions) mockOwnersFiles(files map[string]string) {
+	listing := []byte{}
+
+	for filePath, contents := range files {
+		testOpts.mockFile(filePath, contents)
+		listing = fmt.Appendf(listing, "%s\x00", filePath)
+	}
+
+	testOpts.Mock.On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z", "--cached", "--others", "--exclude-standard"}).
+		Return(listing, nil)
+}
+
+const generatedCodeowners = `# This file is generated by 'gh codeowners generate' from the OWNERS and .owners.yml files in the
+# repository. Don't edit it by hand, edit those files and run 'gh codeowners generate' again.
+
+* @org/everyone # OWNERS
+/app/ @org/everyone @org/backend # app/.owners.yml
+/app/*.sql @org/everyone @org/backend @org/dba # app/.owners.yml:3
+/app/secure/ @org/security # app/secure/OWNERS
+`
+
+func TestMainCoreGenerate(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockOwnersFiles(map[string]string{
+		"OWNERS":            "@org/everyone\n",
+		"app/.owners.yml":   "owners: [\"@org/backend\"]\nfiles:\n  - pattern: \"*.sql\"\n    owners: [\"@org/dba\"]\n",
+		"app/secure/OWNERS": "set noparent\n@org/security\n",
+	})
+	testOpts.mockMissingFile(".github/CODEOWNERS")
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), generatedCodeowners).Return(nil)
+
+	err := mainCore(testOpts.toActual(), []string{"generate"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Wrote .github/CODEOWNERS from 3 OWNERS files\n", testOpts.Out.String())
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), generatedCodeowners)
+}
+
+func TestMainCoreGenerate_check(t *testing.T) {
+	ownersFiles := map[string]string{
+		"OWNERS":            "@org/everyone\n",
+		"app/.owners.yml":   "owners: [\"@org/backend\"]\nfiles:\n  - pattern: \"*.sql\"\n    owners: [\"@org/dba\"]\n",
+		"app/secure/OWNERS": "set noparent\n@org/security\n",
+	}
+
+	testOpts := newTestRootOpts(t)
+	testOpts.mockOwnersFiles(ownersFiles)
+	testOpts.mockFile(".github/CODEOWNERS", generatedCodeowners)
+
+	err := mainCore(testOpts.toActual(), []string{"generate", "--check"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ".github/CODEOWNERS is up to date\n", testOpts.Out.String())
+
+	// A hand edit makes the file out of date
+	testOpts = newTestRootOpts(t)
+	testOpts.mockOwnersFiles(ownersFiles)
+	testOpts.mockFile(".github/CODEOWNERS", generatedCodeowners+"/docs/ @octocat\n")
+
+	err = mainCore(testOpts.toActual(), []string{"generate", "--check"})
+
+	assert.EqualError(t, err, ".github/CODEOWNERS is out of date, run 'gh codeowners generate' to update it")
+	testOpts.Mock.AssertNotCalled(t, "WriteFile", mock.Anything, mock.Anything)
+}
+
+func TestMainCoreGenerate_errors(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockOwnersFiles(map[string]string{
+		"OWNERS":          "@org/everyone\nnot an owner\n",
+		"app/.owners.yml": "owner: [\"@org/backend\"]\n",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"generate"})
+
+	assert.EqualError(t, err, "found 2 errors in the OWNERS files")
+	assert.Equal(t, `OWNERS:2:1: error: unrecognized line 'not an owner', expected an owner, per-file, include or set noparent
+app/.owners.yml:1:1: error: invalid YAML: unknown key 'owner'
+Error: found 2 errors in the OWNERS files
+`, testOpts.Err.String())
+}
+
+func TestMainCoreFmt(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"# Owners",
+		"/docs/  @Org/Docs @org/docs",
+		"/api/ @org/api",
+	})
+
+	err := mainCore(testOpts.toActual(), []string{"fmt"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# Owners\n/api/  @org/api\n/docs/ @org/docs\n", testOpts.Out.String())
+}
+
+func TestMainCoreFmt_check(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/docs/  @org/docs"})
+
+	err := mainCore(testOpts.toActual(), []string{"fmt", "--check"})
+
+	assert.EqualError(t, err, ".github/CODEOWNERS is not formatted, run 'gh codeowners fmt --write' to format it")
+	testOpts.Mock.AssertNotCalled(t, "WriteFile", mock.Anything, mock.Anything)
+
+	testOpts = newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/docs/ @org/docs", ""})
+
+	err = mainCore(testOpts.toActual(), []string{"fmt", "--check"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ".github/CODEOWNERS is formatted\n", testOpts.Out.String())
+}
+
+func TestMainCoreFmt_checkCRLF(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"# Owners\r", "/api/  @org/api\r", "/docs/ @org/docs\r", ""})
+
+	err := mainCore(testOpts.toActual(), []string{"fmt", "--check"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ".github/CODEOWNERS is formatted\n", testOpts.Out.String())
+
+	testOpts = newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/docs/ @org/docs\r", "/api/ @org/api\r", ""})
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), "/api/  @org/api\r\n/docs/ @org/docs\r\n").Return(nil)
+
+	err = mainCore(testOpts.toActual(), []string{"fmt", "--write"})
+
+	assert.NoError(t, err)
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), "/api/  @org/api\r\n/docs/ @org/docs\r\n")
+}
+
+func TestMainCoreFmt_write(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/docs/  @org/docs @ORG/DOCS"})
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), "/docs/ @org/docs\n").Return(nil)
+
+	err := mainCore(testOpts.toActual(), []string{"fmt", "--write"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Formatted .github/CODEOWNERS\n", testOpts.Out.String())
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), "/docs/ @org/docs\n")
+}
+
+func TestMainCoreAdd(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"*                @org/everyone",
+		"/services/       @org/services",
+		"/services/auth/  @org/auth",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("services/billing/invoice.go\x00services/auth/login.go\x00README.md\x00"), nil)
+
+	written := "*                @org/everyone\n/services/       @org/services\n/services/auth/  @org/auth\n/services/billing/ @org/billing\n"
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written).Return(nil)
+
+	err := mainCore(testOpts.toActual(), []string{"add", "/services/billing/", "@org/billing", "--yes"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Adding line 4: /services/billing/ @org/billing
+
+Files that switched owners:
+  services/billing/invoice.go: @org/services -> @org/billing
+
+Changes per owner:
+  @org/billing: +1 -0
+  @org/services: +0 -1
+1 of 3 files would change owners
+Wrote .github/CODEOWNERS
+`, testOpts.Out.String())
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written)
+}
+
+func TestMainCoreRemove_dryRun(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @org/everyone",
+		"/docs/ @org/docs",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("docs/index.md\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"remove", "/docs/", "--dry-run"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Removing line 2: /docs/ @org/docs
+
+Files that switched owners:
+  docs/index.md: @org/docs -> @org/everyone
+
+Changes per owner:
+  @org/docs: +0 -1
+  @org/everyone: +1 -0
+1 of 1 files would change owners
+`, testOpts.Out.String())
+	testOpts.Mock.AssertNotCalled(t, "WriteFile", mock.Anything, mock.Anything)
+
+	testOpts = newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/docs/ @org/docs"})
+
+	err = mainCore(testOpts.toActual(), []string{"remove", "/api/"})
+
+	assert.EqualError(t, err, "there is no rule for '/api/'")
+}
+
+func TestMainCoreTransfer(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"# Payments",
+		"/billing/  @org/payments # money",
+		"/invoices/ @org/payments @octocat",
+		"/docs/     @org/docs",
+	})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("billing/main.go\x00invoices/pdf.go\x00docs/index.md\x00"), nil)
+
+	testOpts.Prompter.Mock.On("Confirm", "Write the changes to .github/CODEOWNERS?", true).Return(true, nil)
+
+	written := "# Payments\n/billing/  @org/billing # money\n/invoices/ @org/billing @octocat\n/docs/     @org/docs\n"
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written).Return(nil)
+
+	err := mainCore(testOpts.toActual(), []string{"transfer", "@Org/Payments", "@org/billing"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Changing line 2: /billing/  @org/payments # money
+             to: /billing/  @org/billing # money
+Changing line 3: /invoices/ @org/payments @octocat
+             to: /invoices/ @org/billing @octocat
+
+Files that switched owners:
+  billing/main.go: @org/payments -> @org/billing
+  invoices/pdf.go: @org/payments @octocat -> @org/billing @octocat
+
+Changes per owner:
+  @org/billing: +2 -0
+  @org/payments: +0 -2
+2 of 3 files would change owners
+Wrote .github/CODEOWNERS
+`, testOpts.Out.String())
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written)
+}
+
+func TestMainCoreTransfer_notConfirmed(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/billing/ @octocat"})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("billing/main.go\x00"), nil)
+
+	testOpts.Prompter.Mock.On("Confirm", "Write the changes to .github/CODEOWNERS?", true).Return(false, nil)
+
+	err := mainCore(testOpts.toActual(), []string{"transfer", "@octocat", "@hubot"})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(testOpts.Out.String(), "Nothing was written\n"))
+	testOpts.Mock.AssertNotCalled(t, "WriteFile", mock.Anything, mock.Anything)
+
+	testOpts = newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/billing/ @octocat"})
+
+	err = mainCore(testOpts.toActual(), []string{"transfer", "@someone", "@hubot"})
+
+	assert.EqualError(t, err, "'@someone' doesn't own anything")
+}
+
+func TestMainCoreRenameTeam(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/billing/ @org/payments @octocat"})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("README.md\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"rename-team", "@org/payments", "billing", "--dry-run"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Changing line 1: /billing/ @org/payments @octocat
+             to: /billing/ @org/billing @octocat
+
+No files would change owners
+`, testOpts.Out.String())
+
+	err = mainCore(newTestRootOpts(t).toActual(), []string{"rename-team", "@octocat", "hubot"})
+
+	assert.EqualError(t, err, "'@octocat' is not a team, use transfer to replace users and emails")
+}
+
+// mockHistory makes git log report the given commits, each a timestamp, email and name followed by
+// numstat lines
+func (testOpts *TestRootCmdOptions) mockHistory(since string, commits ...[]string) {
+	var logOutput strings.Builder
+
+	for _, commit := range commits {
+		logOutput.WriteString("\x1e" + strings.Join(commit[:3], "\x1f") + "\x00\n")
+
+		for _, change := range commit[3:] {
+			logOutput.WriteString(change + "\x00")
+		}
+	}
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "log", "--no-merges", "--no-renames", "--numstat", "-z", "--format=%x1e%at%x1f%aE%x1f%aN", "--since=" + since}).
+		Return([]byte(logOutput.String()), nil)
+}
+
+func TestMainCoreSuggest(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/src/ @org/core"})
+	testOpts.mockFile(".github/codeowners-teams.yml", "\"@org/build\":\n  - alice@example.com\n  - Bob Smith\n")
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("src/main.go\x00tools/build.sh\x00tools/release/tag.sh\x00docs/index.md\x00docs/guide.md\x00LICENSE\x00"), nil)
+
+	testOpts.mockHistory("1 year ago",
+		[]string{"1717200000", "alice@example.com", "Alice", "10\t2\ttools/build.sh", "5\t0\tsrc/main.go"},
+		[]string{"1717100000", "bob@example.com", "Bob Smith", "3\t1\ttools/release/tag.sh"},
+		[]string{"1717000000", "12345+octocat@users.noreply.github.com", "Mona", "-\t-\tdocs/logo.png", "40\t0\tdocs/index.md"},
+	)
+
+	err := mainCore(testOpts.toActual(), []string{"suggest"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/docs/  @octocat\n/tools/ @org/build\n", testOpts.Out.String())
+	assert.Equal(t, "Suggested owners for 4 of 5 unowned files\n", testOpts.Err.String())
+}
+
+func TestMainCoreSuggest_write(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/src/ @org/core"})
+	testOpts.mockMissingFile(".github/codeowners-teams.yml")
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("src/main.go\x00tools/build.sh\x00"), nil)
+
+	testOpts.mockHistory("6 months ago",
+		[]string{"1717200000", "alice@example.com", "Alice", "10\t2\ttools/build.sh"},
+	)
+
+	written := "/src/ @org/core\n/tools/ alice@example.com\n"
+	testOpts.Mock.On("WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written).Return(nil)
+
+	err := mainCore(testOpts.toActual(), []string{"suggest", "--since", "6 months ago", "--write"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Adding line 2: /tools/ alice@example.com
+
+Files that gained owners:
+  tools/build.sh: alice@example.com
+
+Changes per owner:
+  alice@example.com: +1 -0
+1 of 2 files would change owners
+Wrote .github/CODEOWNERS
+`, testOpts.Out.String())
+	testOpts.Mock.AssertCalled(t, "WriteFile", filepath.Join(testOpts.Root, ".github/CODEOWNERS"), written)
+}
+
+func TestMainCoreSuggest_intentionallyUnowned(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/src/ @org/core",
+		"/docs/generated/",
+	})
+	testOpts.mockMissingFile(".github/codeowners-teams.yml")
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("src/main.go\x00docs/index.md\x00docs/generated/api.md\x00"), nil)
+
+	testOpts.mockHistory("1 year ago",
+		[]string{"1717200000", "alice@example.com", "Alice", "10\t2\tdocs/index.md", "500\t0\tdocs/generated/api.md"},
+	)
+
+	err := mainCore(testOpts.toActual(), []string{"suggest"})
+
+	// A rule for /docs/ would take the generated files back from the rule without owners
+	assert.NoError(t, err)
+	assert.Equal(t, "/docs/index.md alice@example.com\n", testOpts.Out.String())
+	assert.Equal(t, "Suggested owners for 1 of 1 unowned files\n", testOpts.Err.String())
+}
+
+func TestMainCoreSuggest_everythingOwned(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"* @org/core"})
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("src/main.go\x00"), nil)
+
+	err := mainCore(testOpts.toActual(), []string{"suggest"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Every file has an owner\n", testOpts.Out.String())
+}
+
+func TestMainCoreStale(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @org/everyone",
+		"/billing/ @org/payments",
+		"/docs/ @octocat",
+	})
+	testOpts.mockFile(".github/codeowners-teams.yml", "\"@org/payments\": [alice@example.com]\n\"@org/billing\": [bob@example.com, Dave]\n")
+
+	testOpts.Mock.
+		On("GitExec", []string{"-C", testOpts.Root, "ls-files", "-z"}).
+		Return([]byte("billing/invoice.go\x00docs/index.md\x00README.md\x00"), nil)
+
+	testOpts.mockHistory("6 months ago",
+		[]string{"1717200000", "bob@example.com", "Bob", "10\t2\tbilling/invoice.go"},
+		[]string{"1717100000", "dave@example.com", "Dave", "1\t1\tbilling/invoice.go"},
+		[]string{"1717000000", "carol@example.com", "Carol", "1\t0\tbilling/invoice.go", "4\t0\tdocs/index.md"},
+		[]string{"1716900000", "12345+octocat@users.noreply.github.com", "Mona", "4\t0\tdocs/index.md"},
+	)
+
+	err := mainCore(testOpts.toActual(), []string{"stale"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Stale rules, their owners haven't changed their files since 6 months ago but others have:
+  line 2: /billing/ @org/payments
+    changed by @org/billing (2 commits), carol@example.com (1 commit)
+1 stale rules out of 3
+`, testOpts.Out.String())
+}
+
+func TestMainCoreTest(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"* @org/everyone",
+		"/docs/ @org/writers",
+		"/src/ @org/core @octocat",
+		"LICENSE",
+	})
+	testOpts.mockFile(".github/codeowners-tests.yml", `src/main.go: "@Octocat @org/core"
+docs/index.md: ["@org/docs"]
+LICENSE: []
+README.md: []
+`)
+
+	err := mainCore(testOpts.toActual(), []string{"test"})
+
+	assert.EqualError(t, err, "2 of 4 assertions failed")
+	assert.Equal(t, `FAIL docs/index.md (.github/codeowners-tests.yml:2)
+  - @org/docs
+  + @org/writers
+  Rule: line 2: /docs/ @org/writers
+FAIL README.md (.github/codeowners-tests.yml:4)
+  + @org/everyone
+  Rule: line 1: * @org/everyone
+`, testOpts.Out.String())
+}
+
+func TestMainCoreTest_passes(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/src/ @org/core"})
+	testOpts.Mock.On("ReadFile", "tests.yml").
+		Return(&cmd.File{Reader: bytes.NewBufferString("src/main.go: \"@org/core\"\nREADME.md:\n"), Close: func() error { return nil }}, nil)
+
+	err := mainCore(testOpts.toActual(), []string{"test", "--file", "tests.yml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2 assertions passed\n", testOpts.Out.String())
+}
+
+func TestMainCoreTest_noRule(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{"/src/ @org/core"})
+	testOpts.mockFile(".github/codeowners-tests.yml", "README.md: \"@org/docs\"\n")
+
+	err := mainCore(testOpts.toActual(), []string{"test"})
+
+	assert.EqualError(t, err, "1 of 1 assertions failed")
+	assert.Equal(t, "FAIL README.md (.github/codeowners-tests.yml:1)\n  - @org/docs\n  Rule: no rule matches the file\n", testOpts.Out.String())
+}
+
+func TestMainCoreReport_ownerKindFilteredOut(t *testing.T) {
+	newOpts := func() *TestRootCmdOptions {
+		testOpts := newTestRootOpts(t)
+
+		testOpts.mockCodeowners([]string{
+			"api/ @org/api",
+			"scripts/ @octocat",
+		})
+
+		testOpts.mockWorkingDirectory([]string{
+			"api/main.go",
+			"scripts/build.sh",
+			"README.md",
+		})
+
+		return testOpts
+	}
+
+	t.Run("table", func(t *testing.T) {
+		testOpts := newOpts()
+
+		err := mainCore(testOpts.toActual(), []string{"report", "--owner-kind", "team"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "@org/api: 1\nFiles that are unowned: 1\nFiles without owners of the chosen kinds: 1\n", testOpts.Out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		testOpts := newOpts()
+
+		err := mainCore(testOpts.toActual(), []string{"report", "--owner-kind", "team", "--format", "json"})
+
+		assert.NoError(t, err)
+		assert.Contains(t, testOpts.Out.String(), "\"unownedFiles\": 1,\n  \"intentionallyUnownedFiles\": 0,\n  \"filteredOwnerFiles\": 1,\n")
+	})
+}
+
+func TestMainCoreExplain_duplicateOwners(t *testing.T) {
+	testOpts := newTestRootOpts(t)
+
+	testOpts.mockCodeowners([]string{
+		"/api/ @org/api @Org/API docs@example.com @org/api",
+	})
+
+	testOpts.mockCurrentDirectory("")
+
+	err := mainCore(testOpts.toActual(), []string{"explain", "api/main.go"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `api/main.go
+  Owners: @org/api docs@example.com
+  Rule: line 1: /api/ @org/api @Org/API docs@example.com @org/api
+`, testOpts.Out.String())
+}